@@ -0,0 +1,38 @@
+package api
+
+import (
+	"ThinkBank-backend/internal/db"
+	"ThinkBank-backend/internal/vector"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RegisterEmbeddingRoutes 注册 /admin/embeddings，查看各模型的向量覆盖情况
+func RegisterEmbeddingRoutes(app fiber.Router) {
+	app.Get("/admin/embeddings", func(c *fiber.Ctx) error {
+		var models []struct {
+			ModelName    string `json:"modelName"`
+			ModelVersion string `json:"modelVersion"`
+			Dimension    int    `json:"dimension"`
+			Count        int64  `json:"count"`
+		}
+		err := db.Instance().Raw(`
+            SELECT model_name, model_version, dimension, COUNT(*) AS count
+            FROM embeddings
+            GROUP BY model_name, model_version, dimension
+            ORDER BY model_name, model_version
+        `).Scan(&models).Error
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		resp := fiber.Map{
+			"active": vector.ActiveModel(),
+			"models": models,
+		}
+		if target, ok := vector.TargetModel(); ok {
+			resp["target"] = target
+		}
+		return c.JSON(resp)
+	})
+}
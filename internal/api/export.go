@@ -0,0 +1,333 @@
+package api
+
+import (
+	"ThinkBank-backend/internal/api/search"
+	"ThinkBank-backend/internal/db"
+	"ThinkBank-backend/internal/model"
+	"ThinkBank-backend/internal/service"
+	"ThinkBank-backend/internal/util"
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var errGPSNotStripped = errors.New("file is in a private place and its location cannot be removed")
+
+const (
+	exportBatchSize = 200
+	maxExportFiles  = 10000
+)
+
+// exportEntry manifest 中的一条记录
+type exportEntry struct {
+	ID        uint              `json:"id"`
+	Path      string            `json:"path"` // 压缩包内路径，文件缺失时为空
+	FileName  string            `json:"fileName"`
+	Type      string            `json:"type"`
+	Caption   string            `json:"caption"`
+	Tags      []string          `json:"tags"`
+	Metadata  map[string]string `json:"metadata"`
+	TakenAt   *time.Time        `json:"takenAt"`
+	Latitude  *float64          `json:"latitude"`
+	Longitude *float64          `json:"longitude"`
+	CreatedAt time.Time         `json:"createdAt"`
+	Error     string            `json:"error,omitempty"` // 未写入压缩包的原因
+}
+
+// RegisterExportRoutes 注册 /export?ids=1,2,3 或 /export?query=，把选中的文件或搜索结果打包为 ZIP 流式下载。
+// 目前没有相册，album 参数返回 400
+func RegisterExportRoutes(app fiber.Router, modelService service.ModelService, original, normalized service.FileService) {
+	app.Get("/export", func(c *fiber.Ctx) error {
+		variant := c.Query("variant", "original")
+		if variant != "original" && variant != "normalized" {
+			return c.Status(400).JSON(fiber.Map{"error": "variant must be original or normalized"})
+		}
+		manifest := c.Query("manifest", "json")
+		if manifest != "json" && manifest != "csv" {
+			return c.Status(400).JSON(fiber.Map{"error": "manifest must be json or csv"})
+		}
+
+		ids, err := exportIDs(c, modelService)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		if len(ids) == 0 {
+			return c.Status(400).JSON(fiber.Map{"error": "nothing to export"})
+		}
+
+		c.Set(fiber.HeaderContentType, "application/zip")
+		c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(
+			`attachment; filename="thinkbank-export-%s.zip"`, time.Now().Format("20060102-150405")))
+		c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+			if err := writeExport(w, ids, original, normalized, variant, manifest); err != nil {
+				// 响应头已发送，只能记录日志，客户端会收到损坏的压缩包
+				log.Println("Export failed:", err)
+			}
+		})
+		return nil
+	})
+}
+
+// exportIDs 从 ids=1,2,3 或 query（混合搜索结果）解析要导出的文件，重复的 ID 只导出一次
+func exportIDs(c *fiber.Ctx, modelService service.ModelService) ([]uint, error) {
+	if c.Query("album") != "" {
+		return nil, errors.New("album export is not supported, use ids or query")
+	}
+	if val := c.Query("ids"); val != "" {
+		parts := strings.Split(val, ",")
+		if len(parts) > maxExportFiles {
+			return nil, fmt.Errorf("at most %d files per export", maxExportFiles)
+		}
+		ids := make([]uint, 0, len(parts))
+		seen := make(map[uint]bool, len(parts))
+		for _, p := range parts {
+			id, err := strconv.ParseUint(strings.TrimSpace(p), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid id: %s", p)
+			}
+			// 同一文件写入两次会在压缩包中产生同名条目
+			if !seen[uint(id)] {
+				seen[uint(id)] = true
+				ids = append(ids, uint(id))
+			}
+		}
+		return ids, nil
+	}
+
+	if query := c.Query("query"); query != "" {
+		limit, err := strconv.Atoi(c.Query("limit", "50"))
+		if err != nil || limit <= 0 {
+			return nil, fmt.Errorf("invalid limit value")
+		}
+		page, err := search.PageParams{Limit: limit}.Resolve()
+		if err != nil {
+			return nil, err
+		}
+		vectorOpts, err := search.VectorParams{}.Resolve()
+		if err != nil {
+			return nil, err
+		}
+		filter, err := search.FilterFromQuery(c)
+		if err != nil {
+			return nil, err
+		}
+		files, _, err := search.ByText(query, modelService, page, vectorOpts, search.DefaultFusionOptions(), filter)
+		if err != nil {
+			return nil, err
+		}
+		ids := make([]uint, len(files))
+		for i, f := range files {
+			ids[i] = f.ID
+		}
+		return ids, nil
+	}
+
+	return nil, fmt.Errorf("ids or query is required")
+}
+
+// writeExport 分批读取记录并逐个写入压缩包，最后写入 manifest。
+// 位于私密地点内的照片去除定位：manifest 不含经纬度，JPEG 去除 GPS 信息，
+// 其他格式的原图无法处理，改为导出规范化后的 JPEG。无法去除定位的文件不导出，原因记录在 manifest 中
+func writeExport(w io.Writer, ids []uint, original, normalized service.FileService, variant, manifest string) error {
+	zw := zip.NewWriter(w)
+	entries := make([]exportEntry, 0, len(ids))
+
+	for start := 0; start < len(ids); start += exportBatchSize {
+		batch := ids[start:min(start+exportBatchSize, len(ids))]
+
+		var files []model.File
+		if err := db.Instance().Where("id IN ?", batch).Find(&files).Error; err != nil {
+			return err
+		}
+		var geos []model.Geo
+		if err := db.Instance().Where("id IN ?", batch).Find(&geos).Error; err != nil {
+			return err
+		}
+		idToGeo := make(map[uint]model.Geo, len(geos))
+		for _, g := range geos {
+			idToGeo[g.ID] = g
+		}
+		var privateIDs []uint
+		err := db.Instance().Raw(`
+            SELECT g.id FROM geos g
+            WHERE g.id IN ?
+              AND EXISTS (SELECT 1 FROM places p WHERE p.private AND ST_Intersects(p.geom, g.geom))
+        `, batch).Scan(&privateIDs).Error
+		if err != nil {
+			return err
+		}
+		private := make(map[uint]bool, len(privateIDs))
+		for _, id := range privateIDs {
+			private[id] = true
+		}
+		idToFile := make(map[uint]model.File, len(files))
+		for _, f := range files {
+			idToFile[f.ID] = f
+		}
+
+		// 保持请求中的顺序
+		for _, id := range batch {
+			f, ok := idToFile[id]
+			if !ok {
+				continue
+			}
+			entry := exportEntry{
+				ID:        f.ID,
+				FileName:  f.FileName,
+				Type:      f.Type,
+				Caption:   f.Caption,
+				Tags:      f.Tags,
+				Metadata:  f.Metadata,
+				TakenAt:   f.TakenAt,
+				CreatedAt: f.CreatedAt,
+			}
+			if g, ok := idToGeo[id]; ok && !private[id] {
+				lat, lng := g.Latitude, g.Longitude
+				entry.Latitude, entry.Longitude = &lat, &lng
+			}
+
+			fs, src, fileVariant := original, f.OriginalFilePath, variant
+			if variant == "normalized" || (private[id] && !isJPEG(f.OriginalFilePath) && f.FilePath != "") {
+				fs, src, fileVariant = normalized, f.FilePath, "normalized"
+			}
+			archivePath := exportPath(f, src, fileVariant)
+			if err := copyToZip(zw, fs, src, archivePath, f.CreatedAt, private[id]); err != nil {
+				log.Printf("Skipping file %d in export: %v", f.ID, err)
+				entry.Error = err.Error()
+			} else {
+				entry.Path = archivePath
+			}
+			entries = append(entries, entry)
+		}
+	}
+
+	if err := writeManifest(zw, entries, manifest); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// exportPath 压缩包内路径，以 ID 为前缀避免重名
+func exportPath(f model.File, src, variant string) string {
+	if variant == "normalized" {
+		return fmt.Sprintf("files/%d%s", f.ID, path.Ext(src))
+	}
+	return fmt.Sprintf("files/%d_%s", f.ID, path.Base(f.FileName))
+}
+
+func isJPEG(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	return ext == ".jpg" || ext == ".jpeg"
+}
+
+// copyToZip 把存储中的文件写入压缩包，stripGPS 时去除 JPEG 中的定位
+func copyToZip(zw *zip.Writer, fs service.FileService, src, archivePath string, modified time.Time, stripGPS bool) error {
+	if src == "" {
+		return fmt.Errorf("file not stored yet")
+	}
+	subPath, ok := fs.SubPath(src)
+	if !ok {
+		return fmt.Errorf("unknown storage path %s", src)
+	}
+	r, err := fs.Open(subPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := r.Close(); err != nil {
+			log.Println("Failed to close exported file:", err)
+		}
+	}()
+
+	// 需要去除定位时先在内存中处理，失败则不写入压缩包
+	var data []byte
+	if stripGPS {
+		if data, err = io.ReadAll(r); err != nil {
+			return err
+		}
+		stripped, ok := util.StripGPS(data)
+		if !ok {
+			return errGPSNotStripped
+		}
+		data = stripped
+	}
+
+	// 图片本身已压缩，直接存储以节省 CPU
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: archivePath, Method: zip.Store, Modified: modified})
+	if err != nil {
+		return err
+	}
+	if !stripGPS {
+		_, err = io.Copy(w, r)
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func writeManifest(zw *zip.Writer, entries []exportEntry, format string) error {
+	w, err := zw.Create("manifest." + format)
+	if err != nil {
+		return err
+	}
+
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	}
+
+	cw := csv.NewWriter(w)
+	header := []string{"id", "path", "fileName", "type", "caption", "tags", "metadata", "takenAt", "latitude", "longitude", "createdAt", "error"}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		tags, _ := json.Marshal(e.Tags)
+		metadata, _ := json.Marshal(e.Metadata)
+		row := []string{
+			strconv.FormatUint(uint64(e.ID), 10),
+			e.Path,
+			e.FileName,
+			e.Type,
+			e.Caption,
+			string(tags),
+			string(metadata),
+			formatTime(e.TakenAt),
+			formatFloat(e.Latitude),
+			formatFloat(e.Longitude),
+			e.CreatedAt.Format(time.RFC3339),
+			e.Error,
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func formatFloat(f *float64) string {
+	if f == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*f, 'f', -1, 64)
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestExportIDs(t *testing.T) {
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		ids, err := exportIDs(c, nil)
+		if err != nil {
+			return c.Status(400).SendString(err.Error())
+		}
+		return c.JSON(ids)
+	})
+
+	cases := []struct {
+		name    string
+		query   string
+		want    []uint
+		wantErr bool
+	}{
+		{name: "ids in order", query: "ids=3,1,2", want: []uint{3, 1, 2}},
+		{name: "duplicates removed", query: "ids=3,1,3,%201,2", want: []uint{3, 1, 2}},
+		{name: "invalid id", query: "ids=1,x", wantErr: true},
+		{name: "album not supported", query: "album=holiday", wantErr: true},
+		{name: "album with ids", query: "album=holiday&ids=1", wantErr: true},
+		{name: "nothing selected", query: "", wantErr: true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			resp, err := app.Test(httptest.NewRequest("GET", "/?"+c.query, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			if c.wantErr {
+				if resp.StatusCode != 400 {
+					t.Errorf("status = %d, want 400", resp.StatusCode)
+				}
+				return
+			}
+			var got []uint
+			if err := json.Unmarshal(body, &got); err != nil {
+				t.Fatalf("status %d: %s", resp.StatusCode, body)
+			}
+			if !slices.Equal(got, c.want) {
+				t.Errorf("ids = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
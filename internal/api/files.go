@@ -1,6 +1,7 @@
 package api
 
 import (
+	"ThinkBank-backend/internal/api/search"
 	"ThinkBank-backend/internal/db"
 	"ThinkBank-backend/internal/model"
 	"log"
@@ -34,8 +35,17 @@ func RegisterFileListRoute(app fiber.Router) {
 
 		offset := (page - 1) * pageSize
 
+		// camera=...、iso>=400 等元数据过滤
+		filter, err := search.FilterFromQuery(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		where, args := filter.Where()
+
 		var files []model.File
-		if err := db.Instance().Limit(pageSize).Offset(offset).Order("created_at DESC").Find(&files).Error; err != nil {
+		if err := db.Instance().Where(where, args...).Limit(pageSize).Offset(offset).Order("created_at DESC").Find(&files).Error; err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"error": err.Error(),
 			})
@@ -52,16 +62,28 @@ func RegisterFileListRoute(app fiber.Router) {
 				"type":             f.Type,
 				"caption":          f.Caption,
 				"tags":             f.Tags,
+				"metadata":         f.Metadata,
+				"takenAt":          f.TakenAt,
 				"createdAt":        f.CreatedAt,
 				"updatedAt":        f.UpdatedAt,
 			}
 		}
 
-		return c.JSON(fiber.Map{
+		resp := fiber.Map{
 			"page":     page,
 			"pageSize": pageSize,
 			"count":    len(files),
 			"files":    result,
-		})
+		}
+		if c.QueryBool("facets") {
+			facets, err := search.ComputeFacets(filter, nil)
+			if err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": err.Error(),
+				})
+			}
+			resp["facets"] = facets
+		}
+		return c.JSON(resp)
 	})
 }
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"ThinkBank-backend/internal/geotag"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RegisterLocationRoutes 注册位置编辑接口：
+// PUT/DELETE /files/:id/location 手动设置或删除位置，POST /geotag/gpx 按 GPX 轨迹为照片定位
+func RegisterLocationRoutes(app fiber.Router) {
+	app.Put("/files/:id/location", func(c *fiber.Ctx) error {
+		id, err := c.ParamsInt("id")
+		if err != nil || id <= 0 {
+			return c.Status(400).JSON(fiber.Map{"error": "invalid id"})
+		}
+		var req struct {
+			Latitude  *float64 `json:"latitude"`
+			Longitude *float64 `json:"longitude"`
+		}
+		if err := c.BodyParser(&req); err != nil || req.Latitude == nil || req.Longitude == nil {
+			return c.Status(400).JSON(fiber.Map{"error": "latitude and longitude are required"})
+		}
+
+		if !geotag.ValidLocation(*req.Latitude, *req.Longitude) {
+			return c.Status(400).JSON(fiber.Map{"error": "latitude must be within [-90, 90] and longitude within [-180, 180]"})
+		}
+
+		geo, err := geotag.SetLocation(uint(id), *req.Latitude, *req.Longitude)
+		if errors.Is(err, geotag.ErrFileNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+		} else if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(fiber.Map{
+			"id":        geo.ID,
+			"latitude":  geo.Latitude,
+			"longitude": geo.Longitude,
+			"createAt":  geo.CreateAt,
+		})
+	})
+
+	app.Delete("/files/:id/location", func(c *fiber.Ctx) error {
+		id, err := c.ParamsInt("id")
+		if err != nil || id <= 0 {
+			return c.Status(400).JSON(fiber.Map{"error": "invalid id"})
+		}
+		if err := geotag.ClearLocation(uint(id)); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	// 表单字段：gpx（文件）、offset（如 -8h、90s）、maxGap、overwrite、dryRun、ids（逗号分隔）
+	app.Post("/geotag/gpx", func(c *fiber.Ctx) error {
+		fileHeader, err := c.FormFile("gpx")
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "gpx is required"})
+		}
+		opts, err := parseGeotagOptions(c)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		file, err := fileHeader.Open()
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "cannot open uploaded file"})
+		}
+		defer func() {
+			if err := file.Close(); err != nil {
+				log.Printf("failed to close uploaded file: %v", err)
+			}
+		}()
+		track, err := geotag.ParseGPX(file)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "invalid GPX: " + err.Error()})
+		}
+
+		result, err := geotag.ApplyTrack(track, opts)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(fiber.Map{
+			"trackPoints": len(track),
+			"trackStart":  track.Start(),
+			"trackEnd":    track.End(),
+			"dryRun":      opts.DryRun,
+			"result":      result,
+		})
+	})
+}
+
+func parseGeotagOptions(c *fiber.Ctx) (geotag.Options, error) {
+	var opts geotag.Options
+	for name, dst := range map[string]*time.Duration{"offset": &opts.Offset, "maxGap": &opts.MaxGap} {
+		if val := c.FormValue(name); val != "" {
+			d, err := time.ParseDuration(val)
+			if err != nil {
+				return opts, errors.New("invalid " + name + " value")
+			}
+			*dst = d
+		}
+	}
+	if opts.MaxGap < 0 {
+		return opts, errors.New("maxGap must not be negative")
+	}
+	for name, dst := range map[string]*bool{"overwrite": &opts.Overwrite, "dryRun": &opts.DryRun} {
+		if val := c.FormValue(name); val != "" {
+			b, err := strconv.ParseBool(val)
+			if err != nil {
+				return opts, errors.New("invalid " + name + " value")
+			}
+			*dst = b
+		}
+	}
+	if val := c.FormValue("ids"); val != "" {
+		for _, part := range strings.Split(val, ",") {
+			id, err := strconv.ParseUint(strings.TrimSpace(part), 10, 64)
+			if err != nil {
+				return opts, errors.New("invalid id: " + part)
+			}
+			opts.IDs = append(opts.IDs, uint(id))
+		}
+	}
+	return opts, nil
+}
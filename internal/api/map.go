@@ -0,0 +1,361 @@
+package api
+
+import (
+	"ThinkBank-backend/internal/api/search"
+	"ThinkBank-backend/internal/db"
+	"ThinkBank-backend/internal/model"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	maxMapZoom = 22
+	// 每个聚合格子在屏幕上约 64 像素（256 像素瓦片）
+	mapCellPixels = 64
+	// Web Mercator 赤道周长（米）
+	mercatorWorldSize = 40075016.685578488
+	maxMapMarkers     = 2000
+)
+
+// MapMarker 地图上的一个聚合点
+type MapMarker struct {
+	Cell         string     `json:"cell"` // 格子编号 x,y，用于 /map/cluster
+	Count        int        `json:"count"`
+	Latitude     float64    `json:"latitude"` // 格子内照片的平均位置
+	Longitude    float64    `json:"longitude"`
+	Bounds       [4]float64 `json:"bounds"` // minLon, minLat, maxLon, maxLat
+	FileID       uint       `json:"fileId"` // 代表照片（最近拍摄）
+	ThumbnailURL string     `json:"thumbnailUrl"`
+}
+
+// mapCell 格子在 geom3857 坐标上的大小（米）
+func mapCell(zoom int) float64 {
+	return mercatorWorldSize / float64(int(256/mapCellPixels)<<zoom)
+}
+
+// mercatorToLonLat 把 Web Mercator 坐标换算为经纬度
+func mercatorToLonLat(x, y float64) (float64, float64) {
+	const r = mercatorWorldSize / (2 * math.Pi)
+	lon := x / r * 180 / math.Pi
+	lat := (2*math.Atan(math.Exp(y/r)) - math.Pi/2) * 180 / math.Pi
+	return lon, lat
+}
+
+// cellBounds 格子的经纬度范围
+func cellBounds(zoom int, cx, cy int64) [4]float64 {
+	size := mapCell(zoom)
+	minLon, minLat := mercatorToLonLat(float64(cx)*size, float64(cy)*size)
+	maxLon, maxLat := mercatorToLonLat(float64(cx+1)*size, float64(cy+1)*size)
+	return [4]float64{minLon, minLat, maxLon, maxLat}
+}
+
+func parseZoom(c *fiber.Ctx) (int, error) {
+	zoom, err := strconv.Atoi(c.Query("zoom"))
+	if err != nil || zoom < 0 || zoom > maxMapZoom {
+		return 0, fmt.Errorf("zoom must be an integer within [0, %d]", maxMapZoom)
+	}
+	return zoom, nil
+}
+
+func parseCell(val string) (int64, int64, error) {
+	parts := strings.Split(val, ",")
+	if len(parts) != 2 {
+		return 0, 0, errors.New("cell must be x,y")
+	}
+	x, err1 := strconv.ParseInt(strings.TrimSpace(parts[0]), 10, 64)
+	y, err2 := strconv.ParseInt(strings.TrimSpace(parts[1]), 10, 64)
+	if err1 != nil || err2 != nil {
+		return 0, 0, errors.New("invalid cell value")
+	}
+	return x, y, nil
+}
+
+// RegisterMapRoutes 注册地图接口：
+// /map/points?bbox=&zoom= 返回范围内按格子聚合的点，/map/cluster?zoom=&cell= 列出格子内的文件。
+// 两者都支持 camera=...、iso>=400 等元数据过滤
+func RegisterMapRoutes(app fiber.Router) {
+	app.Get("/map/points", func(c *fiber.Ctx) error {
+		bbox, err := parseBBox(c.Query("bbox"))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		zoom, err := parseZoom(c)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		filter, err := search.FilterFromQuery(c)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		markers, err := QueryMapMarkers(bbox, zoom, filter)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(fiber.Map{
+			"zoom":      zoom,
+			"markers":   markers,
+			"truncated": len(markers) == maxMapMarkers,
+		})
+	})
+
+	app.Get("/map/cluster", func(c *fiber.Ctx) error {
+		zoom, err := parseZoom(c)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		cx, cy, err := parseCell(c.Query("cell"))
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		page := c.QueryInt("page", 1)
+		pageSize := c.QueryInt("pageSize", 50)
+		if page < 1 || pageSize < 1 || pageSize > 500 {
+			return c.Status(400).JSON(fiber.Map{"error": "page must be >= 1 and pageSize within [1, 500]"})
+		}
+		filter, err := search.FilterFromQuery(c)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		files, total, err := QueryMapCell(zoom, cx, cy, filter, page, pageSize)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(fiber.Map{
+			"page":     page,
+			"pageSize": pageSize,
+			"total":    total,
+			"bounds":   cellBounds(zoom, cx, cy),
+			"files":    files,
+		})
+	})
+}
+
+// QueryMapMarkers 范围内的照片按 zoom 对应的格子聚合，点最多的格子优先，最多 maxMapMarkers 个
+func QueryMapMarkers(bbox [4]float64, zoom int, filter search.Filter) ([]MapMarker, error) {
+	size := mapCell(zoom)
+	where, whereArgs := filter.Where()
+	args := []interface{}{size, size, bbox[0], bbox[1], bbox[2], bbox[3]}
+	args = append(args, whereArgs...)
+	args = append(args, maxMapMarkers)
+
+	var rows []struct {
+		CX        int64
+		CY        int64
+		Count     int
+		Latitude  float64
+		Longitude float64
+		FileID    uint
+	}
+	err := db.Instance().Raw(`
+        WITH pts AS (
+            SELECT geos.id, geos.latitude, geos.longitude,
+                   floor(ST_X(geos.geom3857) / ?)::bigint AS cx,
+                   floor(ST_Y(geos.geom3857) / ?)::bigint AS cy,
+                   COALESCE(files.taken_at, files.created_at) AS taken
+            FROM geos
+            JOIN files ON files.id = geos.id AND files.deleted_at IS NULL
+            WHERE geos.geom && ST_MakeEnvelope(?, ?, ?, ?, 4326)
+              AND geos.geom3857 IS NOT NULL
+              AND `+where+`
+        )
+        SELECT cx, cy, COUNT(*) AS count,
+               AVG(latitude) AS latitude, AVG(longitude) AS longitude,
+               (array_agg(id ORDER BY taken DESC))[1] AS file_id
+        FROM pts
+        GROUP BY cx, cy
+        ORDER BY count DESC
+        LIMIT ?
+    `, args...).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, len(rows))
+	for i, r := range rows {
+		ids[i] = r.FileID
+	}
+	idToFile, err := loadFileMap(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	markers := make([]MapMarker, len(rows))
+	for i, r := range rows {
+		f := idToFile[r.FileID]
+		markers[i] = MapMarker{
+			Cell:         fmt.Sprintf("%d,%d", r.CX, r.CY),
+			Count:        r.Count,
+			Latitude:     r.Latitude,
+			Longitude:    r.Longitude,
+			Bounds:       cellBounds(zoom, r.CX, r.CY),
+			FileID:       r.FileID,
+			ThumbnailURL: f.ThumbnailURL(),
+		}
+	}
+	return markers, nil
+}
+
+// MapFile 格子内的一个文件
+type MapFile struct {
+	ID           uint       `json:"id"`
+	FileName     string     `json:"fileName"`
+	ThumbnailURL string     `json:"thumbnailUrl"`
+	Caption      string     `json:"caption"`
+	TakenAt      *time.Time `json:"takenAt"`
+	Latitude     float64    `json:"latitude"`
+	Longitude    float64    `json:"longitude"`
+}
+
+// QueryMapCell 列出格子内的文件，按拍摄时间倒序，同时返回总数
+func QueryMapCell(zoom int, cx, cy int64, filter search.Filter, page, pageSize int) ([]MapFile, int64, error) {
+	size := mapCell(zoom)
+	where, whereArgs := filter.Where()
+	// 格子边界与 /map/points 中 floor 的取整方式一致：含下界、不含上界
+	cond := `geos.geom3857 && ST_MakeEnvelope(?, ?, ?, ?, 3857)
+        AND ST_X(geos.geom3857) >= ? AND ST_X(geos.geom3857) < ?
+        AND ST_Y(geos.geom3857) >= ? AND ST_Y(geos.geom3857) < ?
+        AND ` + where
+	minX, minY := float64(cx)*size, float64(cy)*size
+	maxX, maxY := minX+size, minY+size
+	args := append([]interface{}{minX, minY, maxX, maxY, minX, maxX, minY, maxY}, whereArgs...)
+
+	from := `
+        FROM files
+        JOIN geos ON geos.id = files.id
+        WHERE files.deleted_at IS NULL AND ` + cond
+
+	var total int64
+	if err := db.Instance().Raw("SELECT COUNT(*)"+from, args...).Scan(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var rows []struct {
+		ID               uint
+		FileName         string
+		Type             string
+		FilePath         string
+		OriginalFilePath string
+		Caption          string
+		TakenAt          *time.Time
+		Latitude         float64
+		Longitude        float64
+	}
+	err := db.Instance().Raw(`
+        SELECT files.id, files.file_name, files.type, files.file_path, files.original_file_path,
+               files.caption, files.taken_at, geos.latitude, geos.longitude`+from+`
+        ORDER BY COALESCE(files.taken_at, files.created_at) DESC, files.id DESC
+        LIMIT ? OFFSET ?
+    `, append(args, pageSize, (page-1)*pageSize)...).Scan(&rows).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	result := make([]MapFile, len(rows))
+	for i, r := range rows {
+		f := model.File{Type: r.Type, FilePath: r.FilePath, OriginalFilePath: r.OriginalFilePath}
+		result[i] = MapFile{
+			ID:           r.ID,
+			FileName:     r.FileName,
+			ThumbnailURL: f.ThumbnailURL(),
+			Caption:      r.Caption,
+			TakenAt:      r.TakenAt,
+			Latitude:     r.Latitude,
+			Longitude:    r.Longitude,
+		}
+	}
+	return result, total, nil
+}
+
+const (
+	// MVT 瓦片坐标范围
+	mvtExtent = 4096
+	// 瓦片内按 64×64 的格子聚合
+	mvtGridCells = 64
+	// 聚合点中最多列出的文件 ID 数，超过时只保留代表照片
+	maxMVTFileIDs = 50
+)
+
+// RegisterTileRoutes 注册矢量瓦片 /tiles/{z}/{x}/{y}.mvt，图层 photos 中每个要素是瓦片内一个格子的聚合点，
+// 属性为 count、fileId（代表照片）与 fileIds（逗号分隔，数量不超过 maxMVTFileIDs 时提供）。
+// 支持与 /map/points 相同的元数据过滤
+func RegisterTileRoutes(app fiber.Router) {
+	app.Get("/tiles/:z/:x/:y.mvt", func(c *fiber.Ctx) error {
+		z, errZ := strconv.Atoi(c.Params("z"))
+		x, errX := strconv.ParseInt(c.Params("x"), 10, 64)
+		y, errY := strconv.ParseInt(c.Params("y"), 10, 64)
+		if errZ != nil || errX != nil || errY != nil || z < 0 || z > maxMapZoom {
+			return c.Status(400).JSON(fiber.Map{"error": "invalid tile coordinates"})
+		}
+		if n := int64(1) << z; x < 0 || x >= n || y < 0 || y >= n {
+			return c.Status(400).JSON(fiber.Map{"error": "tile out of range"})
+		}
+		filter, err := search.FilterFromQuery(c)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		tile, err := QueryTile(z, x, y, filter)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		c.Set(fiber.HeaderCacheControl, "public, max-age=60")
+		if len(tile) == 0 {
+			return c.SendStatus(fiber.StatusNoContent)
+		}
+		c.Set(fiber.HeaderContentType, "application/vnd.mapbox-vector-tile")
+		return c.Send(tile)
+	})
+}
+
+// QueryTile 用 ST_AsMVT 生成瓦片，没有照片时返回空
+func QueryTile(z int, x, y int64, filter search.Filter) ([]byte, error) {
+	cell := mercatorWorldSize / float64(int64(1)<<z) / mvtGridCells
+	where, whereArgs := filter.Where()
+	args := []interface{}{z, x, y}
+	args = append(args, whereArgs...)
+	args = append(args, mvtExtent, maxMVTFileIDs, cell, cell, mvtExtent)
+
+	var tile []byte
+	err := db.Instance().Raw(`
+        WITH bounds AS (
+            SELECT ST_TileEnvelope(?, ?, ?) AS env
+        ),
+        pts AS (
+            SELECT geos.id, geos.geom3857, COALESCE(files.taken_at, files.created_at) AS taken
+            FROM geos
+            JOIN files ON files.id = geos.id AND files.deleted_at IS NULL
+            CROSS JOIN bounds
+            WHERE geos.geom3857 && bounds.env
+              AND `+where+`
+        ),
+        features AS (
+            SELECT
+                ST_AsMVTGeom(
+                    ST_SetSRID(ST_MakePoint(AVG(ST_X(geom3857)), AVG(ST_Y(geom3857))), 3857),
+                    (SELECT env FROM bounds), ?, 0, true
+                ) AS geom,
+                COUNT(*) AS count,
+                (array_agg(id ORDER BY taken DESC))[1] AS "fileId",
+                CASE WHEN COUNT(*) <= ?
+                    THEN array_to_string(array_agg(id ORDER BY taken DESC), ',')
+                END AS "fileIds"
+            FROM pts
+            GROUP BY floor(ST_X(geom3857) / ?), floor(ST_Y(geom3857) / ?)
+        )
+        SELECT ST_AsMVT(features, 'photos', ?, 'geom')
+        FROM features
+        WHERE geom IS NOT NULL
+    `, args...).Row().Scan(&tile)
+	if err != nil {
+		return nil, err
+	}
+	return tile, nil
+}
@@ -0,0 +1,186 @@
+package api
+
+import (
+	"ThinkBank-backend/internal/db"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+const maxPlaceRadius = 100000
+
+// Place 用户定义的地点，范围为多边形或以经纬度为中心的圆
+type Place struct {
+	ID               uint            `json:"id"`
+	Name             string          `json:"name"`
+	Latitude         *float64        `json:"latitude"` // 圆形范围的中心与半径（米），多边形时为空
+	Longitude        *float64        `json:"longitude"`
+	Radius           *float64        `json:"radius"`
+	Geometry         json.RawMessage `json:"geometry" gorm:"type:text"` // GeoJSON MultiPolygon
+	ExcludeFromTrips bool            `json:"excludeFromTrips"`          // 范围内的照片不计入行程
+	Private          bool            `json:"private"`                   // 导出时去除范围内照片的定位
+	PhotoCount       int             `json:"photoCount"`
+	CreatedAt        time.Time       `json:"createdAt"`
+	UpdatedAt        time.Time       `json:"updatedAt"`
+}
+
+// placeRequest 创建或修改地点，radius 与 geometry 二选一
+type placeRequest struct {
+	Name             string          `json:"name"`
+	Latitude         *float64        `json:"latitude"`
+	Longitude        *float64        `json:"longitude"`
+	Radius           *float64        `json:"radius"`
+	Geometry         json.RawMessage `json:"geometry"` // GeoJSON Polygon / MultiPolygon
+	ExcludeFromTrips *bool           `json:"excludeFromTrips"`
+	Private          bool            `json:"private"`
+}
+
+// geomSQL 校验请求并返回生成 geom 的 SQL 及其参数
+func (r placeRequest) geomSQL() (string, []interface{}, error) {
+	if r.Name == "" {
+		return "", nil, errors.New("name is required")
+	}
+	if len(r.Geometry) > 0 {
+		if r.Radius != nil {
+			return "", nil, errors.New("radius and geometry are mutually exclusive")
+		}
+		var g struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(r.Geometry, &g); err != nil || (g.Type != "Polygon" && g.Type != "MultiPolygon") {
+			return "", nil, errors.New("geometry must be a GeoJSON Polygon or MultiPolygon")
+		}
+		return "ST_Multi(ST_SetSRID(ST_GeomFromGeoJSON(?), 4326))", []interface{}{string(r.Geometry)}, nil
+	}
+
+	if r.Latitude == nil || r.Longitude == nil || r.Radius == nil {
+		return "", nil, errors.New("latitude, longitude and radius, or geometry, are required")
+	}
+	if *r.Latitude < -90 || *r.Latitude > 90 || *r.Longitude < -180 || *r.Longitude > 180 {
+		return "", nil, errors.New("latitude must be within [-90, 90] and longitude within [-180, 180]")
+	}
+	if *r.Radius <= 0 || *r.Radius > maxPlaceRadius {
+		return "", nil, errors.New("radius must be within (0, 100000] meters")
+	}
+	// 在球面上按米缓冲
+	return "ST_Multi(ST_Buffer(ST_SetSRID(ST_MakePoint(?, ?), 4326)::geography, ?)::geometry)",
+		[]interface{}{*r.Longitude, *r.Latitude, *r.Radius}, nil
+}
+
+// RegisterPlaceRoutes 注册地点的增删改查 /places。按地点过滤照片使用 place=名称
+func RegisterPlaceRoutes(app fiber.Router) {
+	app.Get("/places", func(c *fiber.Ctx) error {
+		places, err := queryPlaces(0)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(places)
+	})
+
+	app.Post("/places", func(c *fiber.Ctx) error {
+		return savePlace(c, 0)
+	})
+
+	app.Put("/places/:id", func(c *fiber.Ctx) error {
+		id, err := c.ParamsInt("id")
+		if err != nil || id <= 0 {
+			return c.Status(400).JSON(fiber.Map{"error": "invalid id"})
+		}
+		return savePlace(c, uint(id))
+	})
+
+	app.Delete("/places/:id", func(c *fiber.Ctx) error {
+		id, err := c.ParamsInt("id")
+		if err != nil || id <= 0 {
+			return c.Status(400).JSON(fiber.Map{"error": "invalid id"})
+		}
+		res := db.Instance().Exec("DELETE FROM places WHERE id = ?", id)
+		if res.Error != nil {
+			return c.Status(500).JSON(fiber.Map{"error": res.Error.Error()})
+		}
+		if res.RowsAffected == 0 {
+			return c.Status(404).JSON(fiber.Map{"error": "place not found"})
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+}
+
+// savePlace 创建（id 为 0）或整体替换地点
+func savePlace(c *fiber.Ctx, id uint) error {
+	var req placeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+	}
+	geom, geomArgs, err := req.geomSQL()
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	excludeFromTrips := true
+	if req.ExcludeFromTrips != nil {
+		excludeFromTrips = *req.ExcludeFromTrips
+	}
+	// 多边形不保存中心与半径
+	if len(req.Geometry) > 0 {
+		req.Latitude, req.Longitude = nil, nil
+	}
+
+	var taken int64
+	if err := db.Instance().Raw("SELECT COUNT(*) FROM places WHERE name = ? AND id <> ?", req.Name, id).Scan(&taken).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	if taken > 0 {
+		return c.Status(409).JSON(fiber.Map{"error": "a place with this name already exists"})
+	}
+
+	args := append([]interface{}{req.Name, req.Latitude, req.Longitude, req.Radius}, geomArgs...)
+	args = append(args, excludeFromTrips, req.Private)
+	var res *gorm.DB
+	if id == 0 {
+		res = db.Instance().Raw(`
+            INSERT INTO places (name, latitude, longitude, radius, geom, exclude_from_trips, private)
+            VALUES (?, ?, ?, ?, `+geom+`, ?, ?)
+            RETURNING id
+        `, args...).Scan(&id)
+	} else {
+		res = db.Instance().Raw(`
+            UPDATE places
+            SET name = ?, latitude = ?, longitude = ?, radius = ?, geom = `+geom+`,
+                exclude_from_trips = ?, private = ?, updated_at = now()
+            WHERE id = ?
+            RETURNING id
+        `, append(args, id)...).Scan(&id)
+	}
+	if res.Error != nil {
+		return c.Status(400).JSON(fiber.Map{"error": res.Error.Error()})
+	}
+	if res.RowsAffected == 0 {
+		return c.Status(404).JSON(fiber.Map{"error": "place not found"})
+	}
+
+	places, err := queryPlaces(id)
+	if err != nil || len(places) == 0 {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to load saved place"})
+	}
+	return c.JSON(places[0])
+}
+
+// queryPlaces 读取地点及范围内的照片数，id 为 0 时读取全部
+func queryPlaces(id uint) ([]Place, error) {
+	var places []Place
+	err := db.Instance().Raw(`
+        SELECT p.id, p.name, p.latitude, p.longitude, p.radius,
+               ST_AsGeoJSON(p.geom) AS geometry,
+               p.exclude_from_trips, p.private, p.created_at, p.updated_at,
+               (SELECT COUNT(*) FROM geos g WHERE ST_Intersects(p.geom, g.geom)) AS photo_count
+        FROM places p
+        WHERE ? = 0 OR p.id = ?
+        ORDER BY p.name
+    `, id, id).Scan(&places).Error
+	if err != nil {
+		return nil, err
+	}
+	return places, nil
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"ThinkBank-backend/internal/queue"
+	"crypto/subtle"
+	"log"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RegisterReindexRoutes 注册重新处理文件的管理接口，请求需带 Authorization: Bearer <token>。
+// token 为空时不注册，避免任何人都能触发全库重新处理。队列位于进程内，
+// workers 为 false 时本进程没有消费者，拒绝创建任务
+func RegisterReindexRoutes(app fiber.Router, workers bool, token string) {
+	if token == "" {
+		log.Println("ADMIN_TOKEN not set, /admin/reindex is disabled")
+		return
+	}
+	admin := app.Group("/admin/reindex", adminAuth(token))
+
+	admin.Post("", func(c *fiber.Ctx) error {
+		if !workers {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": "reindex is unavailable because queue workers are disabled in this process",
+			})
+		}
+
+		var req queue.ReindexRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+		}
+
+		job, err := queue.StartReindex(req)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusAccepted).JSON(job)
+	})
+
+	admin.Get("", func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{"jobs": queue.ListJobs()})
+	})
+
+	admin.Get("/:id", func(c *fiber.Ctx) error {
+		job, ok := queue.GetJob(c.Params("id"))
+		if !ok {
+			return c.Status(404).JSON(fiber.Map{"error": "job not found"})
+		}
+		return c.JSON(job)
+	})
+}
+
+// adminAuth 校验 Bearer token，按常量时间比较
+func adminAuth(token string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		got, ok := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
+		}
+		return c.Next()
+	}
+}
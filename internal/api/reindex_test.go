@@ -0,0 +1,41 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestReindexRoutesRequireToken(t *testing.T) {
+	cases := []struct {
+		name   string
+		token  string // 服务端配置
+		header string
+		want   int
+	}{
+		{"disabled without token", "", "Bearer secret", fiber.StatusNotFound},
+		{"missing header", "secret", "", fiber.StatusUnauthorized},
+		{"wrong token", "secret", "Bearer nope", fiber.StatusUnauthorized},
+		{"not bearer", "secret", "secret", fiber.StatusUnauthorized},
+		{"valid token", "secret", "Bearer secret", fiber.StatusOK},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			app := fiber.New()
+			RegisterReindexRoutes(app, false, c.token)
+
+			req := httptest.NewRequest("GET", "/admin/reindex", nil)
+			if c.header != "" {
+				req.Header.Set("Authorization", c.header)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != c.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package search
+
+import (
+	"ThinkBank-backend/internal/db"
+)
+
+const facetLimit = 20
+
+// FacetCount 某个取值及其文件数
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// Facets 各维度的分面统计，按数量降序
+type Facets struct {
+	Camera []FacetCount `json:"camera"`
+	Lens   []FacetCount `json:"lens"`
+	Year   []FacetCount `json:"year"`
+	Type   []FacetCount `json:"type"`
+	Tag    []FacetCount `json:"tag"`
+}
+
+// facetQueries 各分面的取值表达式，from 为额外的 FROM 子句
+var facetQueries = []struct {
+	name  string
+	value string
+	from  string
+}{
+	{"camera", "metadata->>'model'", ""},
+	{"lens", "metadata->>'lensModel'", ""},
+	{"year", "EXTRACT(YEAR FROM " + takenExpr + ")::int::text", ""}, // 与年份过滤同在数据库会话时区
+	{"type", "type", ""},
+	{"tag", "tag", ", jsonb_array_elements_text(CASE WHEN jsonb_typeof(tags) = 'array' THEN tags ELSE '[]'::jsonb END) AS tag"},
+}
+
+// ComputeFacets 统计满足过滤条件的文件的分面；ids 不为空时只统计这些文件（如搜索候选集）
+func ComputeFacets(filter Filter, ids []uint) (Facets, error) {
+	where, args := filter.Where()
+	where = "deleted_at IS NULL AND " + where
+	if ids != nil {
+		if len(ids) == 0 {
+			return Facets{}, nil
+		}
+		where += " AND id IN ?"
+		args = append(args, ids)
+	}
+
+	var facets Facets
+	dst := map[string]*[]FacetCount{
+		"camera": &facets.Camera,
+		"lens":   &facets.Lens,
+		"year":   &facets.Year,
+		"type":   &facets.Type,
+		"tag":    &facets.Tag,
+	}
+	for _, q := range facetQueries {
+		var counts []FacetCount
+		err := db.Instance().Raw(`
+            SELECT value, COUNT(*) AS count
+            FROM (
+                SELECT `+q.value+` AS value
+                FROM files`+q.from+`
+                WHERE `+where+`
+            ) v
+            WHERE value IS NOT NULL AND value <> ''
+            GROUP BY value
+            ORDER BY count DESC, value
+            LIMIT ?
+        `, append(args, facetLimit)...).Scan(&counts).Error
+		if err != nil {
+			return Facets{}, err
+		}
+		*dst[q.name] = counts
+	}
+	return facets, nil
+}
@@ -0,0 +1,194 @@
+package search
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// filterKind 过滤字段的取值类型
+type filterKind int
+
+const (
+	filterString filterKind = iota // Metadata 中的字符串，精确匹配，走 GIN 索引
+	filterNumber                   // Metadata 中的数值，走 metadata_number 表达式索引
+	filterType                     // files.type
+	filterTag                      // files.tags
+	filterYear                     // 拍摄年份，缺失时按上传时间
+	filterPlace                    // 位置在指定名称的地点范围内
+)
+
+type filterField struct {
+	kind filterKind
+	key  string // Metadata 中的键
+}
+
+// filterFields 客户端可用的过滤字段
+var filterFields = map[string]filterField{
+	"camera":   {filterString, "model"},
+	"make":     {filterString, "make"},
+	"lens":     {filterString, "lensModel"},
+	"iso":      {filterNumber, "iso"},
+	"focal":    {filterNumber, "focalLength"},
+	"focal35":  {filterNumber, "focalLength35mm"},
+	"aperture": {filterNumber, "fNumber"},
+	"exposure": {filterNumber, "exposureTime"},
+	"rating":   {filterNumber, "rating"},
+	"type":     {filterType, ""},
+	"tag":      {filterTag, ""},
+	"year":     {filterYear, ""},
+	"place":    {filterPlace, ""},
+}
+
+// takenExpr 拍摄时间，缺失时按上传时间，与 idx_files_taken_or_created 一致
+const takenExpr = "COALESCE(taken_at, created_at)"
+
+// yearStart 某年 1 月 1 日零点，按数据库会话时区解释
+const yearStart = "make_timestamptz(?, 1, 1, 0, 0, 0)"
+
+var filterExpr = regexp.MustCompile(`^(\w+)\s*(>=|<=|>|<|=)\s*(.+)$`)
+
+// Filter 文件过滤条件，多个条件之间为 AND
+type Filter struct {
+	conds []string
+	args  []interface{}
+}
+
+// Empty 是否没有任何条件
+func (f Filter) Empty() bool {
+	return len(f.conds) == 0
+}
+
+// Where 返回可直接用于 files 表的条件，没有条件时为 TRUE
+func (f Filter) Where() (string, []interface{}) {
+	if f.Empty() {
+		return "TRUE", nil
+	}
+	return strings.Join(f.conds, " AND "), f.args
+}
+
+// ParseFilter 解析形如 camera=iPhone 15、iso>=400 的表达式
+func ParseFilter(exprs []string) (Filter, error) {
+	var f Filter
+	for _, expr := range exprs {
+		m := filterExpr.FindStringSubmatch(strings.TrimSpace(expr))
+		if m == nil {
+			return Filter{}, fmt.Errorf("invalid filter: %s", expr)
+		}
+		if err := f.add(m[1], m[2], strings.TrimSpace(m[3])); err != nil {
+			return Filter{}, err
+		}
+	}
+	return f, nil
+}
+
+// FilterFromQuery 从 query string 解析过滤条件，忽略非过滤字段。
+// iso>=400 在 query string 中会被拆成键 "iso>" 与值 "400"，这里重新拼回表达式
+func FilterFromQuery(c *fiber.Ctx) (Filter, error) {
+	var exprs []string
+	c.Context().QueryArgs().VisitAll(func(key, value []byte) {
+		expr := string(key)
+		if len(value) > 0 {
+			expr += "=" + string(value)
+		}
+		if m := filterExpr.FindStringSubmatch(expr); m != nil {
+			if _, ok := filterFields[m[1]]; ok {
+				exprs = append(exprs, expr)
+			}
+		}
+	})
+	return ParseFilter(exprs)
+}
+
+// formFilter 从表单的 filter 字段（可重复）解析过滤条件
+func formFilter(c *fiber.Ctx) (Filter, error) {
+	var exprs []string
+	if form, err := c.MultipartForm(); err == nil {
+		exprs = form.Value["filter"]
+	} else if val := c.FormValue("filter"); val != "" {
+		exprs = []string{val}
+	}
+	f, err := ParseFilter(exprs)
+	if err != nil {
+		return Filter{}, err
+	}
+	// GET 请求也可以直接写在 query string 中
+	q, err := FilterFromQuery(c)
+	if err != nil {
+		return Filter{}, err
+	}
+	f.conds = append(f.conds, q.conds...)
+	f.args = append(f.args, q.args...)
+	return f, nil
+}
+
+func (f *Filter) add(name, op, val string) error {
+	field, ok := filterFields[name]
+	if !ok {
+		return fmt.Errorf("unknown filter field: %s", name)
+	}
+
+	switch field.kind {
+	case filterString, filterType, filterTag, filterPlace:
+		if op != "=" {
+			return fmt.Errorf("filter %s only supports =", name)
+		}
+		switch field.kind {
+		case filterString:
+			doc, _ := json.Marshal(map[string]string{field.key: val})
+			f.conds = append(f.conds, "metadata @> ?::jsonb")
+			f.args = append(f.args, string(doc))
+		case filterType:
+			f.conds = append(f.conds, "type = ?")
+			f.args = append(f.args, val)
+		case filterPlace:
+			f.conds = append(f.conds, `EXISTS (
+                SELECT 1 FROM geos pg JOIN places pl ON ST_Intersects(pl.geom, pg.geom)
+                WHERE pg.id = files.id AND pl.name = ?)`)
+			f.args = append(f.args, val)
+		default:
+			doc, _ := json.Marshal([]string{val})
+			f.conds = append(f.conds, "tags @> ?::jsonb")
+			f.args = append(f.args, string(doc))
+		}
+
+	case filterNumber:
+		n, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			return fmt.Errorf("filter %s expects a number", name)
+		}
+		// key 来自固定表，直接拼入以便匹配表达式索引
+		f.conds = append(f.conds, fmt.Sprintf("metadata_number(metadata, '%s') %s ?::numeric", field.key, op))
+		f.args = append(f.args, n)
+
+	case filterYear:
+		year, err := strconv.Atoi(val)
+		if err != nil || year < 1 || year > 9999 {
+			return fmt.Errorf("filter %s expects a year", name)
+		}
+		// 年份边界在数据库会话时区内计算，与年份分面的 EXTRACT(YEAR ...) 一致
+		start, next := year, year+1
+		switch op {
+		case "=":
+			f.conds = append(f.conds, takenExpr+" >= "+yearStart+" AND "+takenExpr+" < "+yearStart)
+			f.args = append(f.args, start, next)
+		case ">=":
+			f.conds = append(f.conds, takenExpr+" >= "+yearStart)
+			f.args = append(f.args, start)
+		case ">":
+			f.conds = append(f.conds, takenExpr+" >= "+yearStart)
+			f.args = append(f.args, next)
+		case "<=":
+			f.conds = append(f.conds, takenExpr+" < "+yearStart)
+			f.args = append(f.args, next)
+		case "<":
+			f.conds = append(f.conds, takenExpr+" < "+yearStart)
+			f.args = append(f.args, start)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFilter(t *testing.T) {
+	cases := []struct {
+		name      string
+		exprs     []string
+		wantWhere string
+		wantArgs  []interface{}
+		wantErr   bool
+	}{
+		{
+			name:      "no filter",
+			wantWhere: "TRUE",
+		},
+		{
+			name:      "metadata string",
+			exprs:     []string{"camera=iPhone 15 Pro"},
+			wantWhere: "metadata @> ?::jsonb",
+			wantArgs:  []interface{}{`{"model":"iPhone 15 Pro"}`},
+		},
+		{
+			name:      "metadata number with spaces",
+			exprs:     []string{" iso >= 400 "},
+			wantWhere: "metadata_number(metadata, 'iso') >= ?::numeric",
+			wantArgs:  []interface{}{400.0},
+		},
+		{
+			name:      "combined with AND",
+			exprs:     []string{"type=image", "tag=beach", "aperture<2.8"},
+			wantWhere: "type = ? AND tags @> ?::jsonb AND metadata_number(metadata, 'fNumber') < ?::numeric",
+			wantArgs:  []interface{}{"image", `["beach"]`, 2.8},
+		},
+		{
+			name:      "year equals",
+			exprs:     []string{"year=2023"},
+			wantWhere: "COALESCE(taken_at, created_at) >= make_timestamptz(?, 1, 1, 0, 0, 0) AND COALESCE(taken_at, created_at) < make_timestamptz(?, 1, 1, 0, 0, 0)",
+			wantArgs:  []interface{}{2023, 2024},
+		},
+		{
+			name:      "year after",
+			exprs:     []string{"year>2023"},
+			wantWhere: "COALESCE(taken_at, created_at) >= make_timestamptz(?, 1, 1, 0, 0, 0)",
+			wantArgs:  []interface{}{2024},
+		},
+		{
+			name:      "year up to",
+			exprs:     []string{"year<=2023"},
+			wantWhere: "COALESCE(taken_at, created_at) < make_timestamptz(?, 1, 1, 0, 0, 0)",
+			wantArgs:  []interface{}{2024},
+		},
+		{name: "unknown field", exprs: []string{"color=red"}, wantErr: true},
+		{name: "not an expression", exprs: []string{"iPhone"}, wantErr: true},
+		{name: "comparison on string field", exprs: []string{"camera>=a"}, wantErr: true},
+		{name: "number expected", exprs: []string{"iso=high"}, wantErr: true},
+		{name: "year out of range", exprs: []string{"year=0"}, wantErr: true},
+		{name: "place comparison", exprs: []string{"place>Home"}, wantErr: true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			f, err := ParseFilter(c.exprs)
+			if (err != nil) != c.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, c.wantErr)
+			}
+			if c.wantErr {
+				return
+			}
+			where, args := f.Where()
+			if where != c.wantWhere {
+				t.Errorf("where = %q, want %q", where, c.wantWhere)
+			}
+			if !reflect.DeepEqual(args, c.wantArgs) {
+				t.Errorf("args = %#v, want %#v", args, c.wantArgs)
+			}
+		})
+	}
+}
+
+func TestParseFilterPlace(t *testing.T) {
+	f, err := ParseFilter([]string{"place=Home"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, args := f.Where()
+	if !reflect.DeepEqual(args, []interface{}{"Home"}) {
+		t.Errorf("args = %#v", args)
+	}
+}
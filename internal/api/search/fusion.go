@@ -0,0 +1,145 @@
+package search
+
+import (
+	"fmt"
+	"sort"
+)
+
+// FusionMethod 混合检索的分数融合策略
+type FusionMethod string
+
+const (
+	// FusionWeighted 直接按 alpha 加权原始分数
+	FusionWeighted FusionMethod = "weighted"
+	// FusionMinMax 先对两路分数做 min-max 归一化，再按 alpha 加权
+	FusionMinMax FusionMethod = "minmax"
+	// FusionRRF Reciprocal Rank Fusion，只使用排名，不依赖分数尺度
+	FusionRRF FusionMethod = "rrf"
+)
+
+const (
+	defaultFusionAlpha = 0.5
+	defaultRRFK        = 60
+)
+
+// FusionOptions 融合参数
+type FusionOptions struct {
+	Method FusionMethod
+	Alpha  float64 // 文本分数权重，向量分数权重为 1-Alpha（weighted / minmax）
+	RRFK   int     // RRF 平滑常数 k（rrf）
+}
+
+// DefaultFusionOptions 默认使用 RRF
+func DefaultFusionOptions() FusionOptions {
+	return FusionOptions{
+		Method: FusionRRF,
+		Alpha:  defaultFusionAlpha,
+		RRFK:   defaultRRFK,
+	}
+}
+
+// Validate 校验融合参数
+func (o FusionOptions) Validate() error {
+	switch o.Method {
+	case FusionWeighted, FusionMinMax, FusionRRF:
+	default:
+		return fmt.Errorf("unknown fusion method: %s", o.Method)
+	}
+	if o.Alpha < 0 || o.Alpha > 1 {
+		return fmt.Errorf("alpha must be within [0, 1]")
+	}
+	if o.RRFK <= 0 {
+		return fmt.Errorf("rrfK must be positive")
+	}
+	return nil
+}
+
+// rankedID 单路检索结果，按相关度降序排列
+type rankedID struct {
+	ID    uint
+	Score float64
+}
+
+// fusedScore 融合后的分数，同时保留两路原始分数便于调试
+type fusedScore struct {
+	ID          uint
+	Score       float64
+	TextScore   *float64
+	VectorScore *float64
+}
+
+// fuse 按指定策略融合文本与向量两路结果，返回按分数降序排列的列表
+func fuse(textResults, vectorResults []rankedID, opts FusionOptions) []fusedScore {
+	byID := make(map[uint]*fusedScore)
+	order := make([]uint, 0, len(textResults)+len(vectorResults))
+	get := func(id uint) *fusedScore {
+		if s, ok := byID[id]; ok {
+			return s
+		}
+		s := &fusedScore{ID: id}
+		byID[id] = s
+		order = append(order, id)
+		return s
+	}
+
+	textNorm := normalizeScores(textResults, opts.Method)
+	vectorNorm := normalizeScores(vectorResults, opts.Method)
+
+	for rank, r := range textResults {
+		s := get(r.ID)
+		score := r.Score
+		s.TextScore = &score
+		switch opts.Method {
+		case FusionRRF:
+			s.Score += 1 / float64(opts.RRFK+rank+1)
+		default:
+			s.Score += opts.Alpha * textNorm[rank]
+		}
+	}
+	for rank, r := range vectorResults {
+		s := get(r.ID)
+		score := r.Score
+		s.VectorScore = &score
+		switch opts.Method {
+		case FusionRRF:
+			s.Score += 1 / float64(opts.RRFK+rank+1)
+		default:
+			s.Score += (1 - opts.Alpha) * vectorNorm[rank]
+		}
+	}
+
+	fused := make([]fusedScore, 0, len(order))
+	for _, id := range order {
+		fused = append(fused, *byID[id])
+	}
+	sort.SliceStable(fused, func(i, j int) bool {
+		return fused[i].Score > fused[j].Score
+	})
+	return fused
+}
+
+// normalizeScores 对 minmax 策略做归一化，其他策略原样返回
+func normalizeScores(results []rankedID, method FusionMethod) []float64 {
+	scores := make([]float64, len(results))
+	for i, r := range results {
+		scores[i] = r.Score
+	}
+	if method != FusionMinMax || len(scores) == 0 {
+		return scores
+	}
+
+	lo, hi := scores[0], scores[0]
+	for _, s := range scores {
+		lo = min(lo, s)
+		hi = max(hi, s)
+	}
+	for i, s := range scores {
+		if hi == lo {
+			// 所有分数相同时视为同等相关
+			scores[i] = 1
+		} else {
+			scores[i] = (s - lo) / (hi - lo)
+		}
+	}
+	return scores
+}
@@ -0,0 +1,113 @@
+package search
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFuse(t *testing.T) {
+	text := []rankedID{{ID: 1, Score: 0.9}, {ID: 2, Score: 0.5}}
+	vec := []rankedID{{ID: 2, Score: 0.8}, {ID: 3, Score: 0.6}}
+
+	cases := []struct {
+		name   string
+		text   []rankedID
+		vector []rankedID
+		opts   FusionOptions
+		want   []fusedScore // 只比较 ID 与 Score
+	}{
+		{
+			name:   "rrf",
+			text:   text,
+			vector: vec,
+			opts:   FusionOptions{Method: FusionRRF, RRFK: 60},
+			want:   []fusedScore{{ID: 2, Score: 1.0/62 + 1.0/61}, {ID: 1, Score: 1.0 / 61}, {ID: 3, Score: 1.0 / 62}},
+		},
+		{
+			name:   "weighted",
+			text:   text,
+			vector: vec,
+			opts:   FusionOptions{Method: FusionWeighted, Alpha: 0.5},
+			want:   []fusedScore{{ID: 2, Score: 0.65}, {ID: 1, Score: 0.45}, {ID: 3, Score: 0.3}},
+		},
+		{
+			name:   "weighted text only",
+			text:   text,
+			vector: vec,
+			opts:   FusionOptions{Method: FusionWeighted, Alpha: 1},
+			want:   []fusedScore{{ID: 1, Score: 0.9}, {ID: 2, Score: 0.5}, {ID: 3, Score: 0}},
+		},
+		{
+			name:   "minmax ties keep first appearance",
+			text:   text,
+			vector: vec,
+			opts:   FusionOptions{Method: FusionMinMax, Alpha: 0.5},
+			want:   []fusedScore{{ID: 1, Score: 0.5}, {ID: 2, Score: 0.5}, {ID: 3, Score: 0}},
+		},
+		{
+			name:   "minmax equal scores",
+			text:   []rankedID{{ID: 1, Score: 0.3}, {ID: 2, Score: 0.3}},
+			vector: nil,
+			opts:   FusionOptions{Method: FusionMinMax, Alpha: 0.4},
+			want:   []fusedScore{{ID: 1, Score: 0.4}, {ID: 2, Score: 0.4}},
+		},
+		{
+			name: "empty",
+			opts: DefaultFusionOptions(),
+			want: []fusedScore{},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := fuse(c.text, c.vector, c.opts)
+			if len(got) != len(c.want) {
+				t.Fatalf("got %d results, want %d: %+v", len(got), len(c.want), got)
+			}
+			for i, w := range c.want {
+				if got[i].ID != w.ID || math.Abs(got[i].Score-w.Score) > 1e-9 {
+					t.Errorf("result %d = {%d %f}, want {%d %f}", i, got[i].ID, got[i].Score, w.ID, w.Score)
+				}
+			}
+		})
+	}
+}
+
+func TestFuseKeepsRawScores(t *testing.T) {
+	got := fuse(
+		[]rankedID{{ID: 1, Score: 0.9}},
+		[]rankedID{{ID: 1, Score: 0.2}, {ID: 2, Score: 0.1}},
+		FusionOptions{Method: FusionMinMax, Alpha: 0.5},
+	)
+	scores := map[uint]fusedScore{}
+	for _, s := range got {
+		scores[s.ID] = s
+	}
+	if s := scores[1]; s.TextScore == nil || *s.TextScore != 0.9 || s.VectorScore == nil || *s.VectorScore != 0.2 {
+		t.Errorf("file 1 raw scores = %v, %v", s.TextScore, s.VectorScore)
+	}
+	if s := scores[2]; s.TextScore != nil || s.VectorScore == nil || *s.VectorScore != 0.1 {
+		t.Errorf("file 2 raw scores = %v, %v", s.TextScore, s.VectorScore)
+	}
+}
+
+func TestFusionOptionsValidate(t *testing.T) {
+	cases := []struct {
+		name    string
+		opts    FusionOptions
+		wantErr bool
+	}{
+		{"default", DefaultFusionOptions(), false},
+		{"weighted", FusionOptions{Method: FusionWeighted, Alpha: 0.3, RRFK: 60}, false},
+		{"unknown method", FusionOptions{Method: "max", Alpha: 0.5, RRFK: 60}, true},
+		{"alpha below range", FusionOptions{Method: FusionMinMax, Alpha: -0.1, RRFK: 60}, true},
+		{"alpha above range", FusionOptions{Method: FusionMinMax, Alpha: 1.1, RRFK: 60}, true},
+		{"zero rrfK", FusionOptions{Method: FusionRRF, Alpha: 0.5, RRFK: 0}, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if err := c.opts.Validate(); (err != nil) != c.wantErr {
+				t.Errorf("err = %v, wantErr %v", err, c.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package search
+
+import (
+	"ThinkBank-backend/internal/vector"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+const (
+	defaultPageLimit = 10
+	maxPageLimit     = 200
+)
+
+// Page 搜索分页参数
+type Page struct {
+	Offset int
+	Limit  int
+}
+
+// PageParams 客户端传入的分页参数，cursor 优先于 offset，topK 为旧参数名
+type PageParams struct {
+	Limit  int    `json:"limit" form:"limit"`
+	TopK   int    `json:"topK" form:"topK"`
+	Offset int    `json:"offset" form:"offset"`
+	Cursor string `json:"cursor" form:"cursor"`
+}
+
+// Resolve 校验并转换为 Page
+func (p PageParams) Resolve() (Page, error) {
+	page := Page{Offset: p.Offset, Limit: p.Limit}
+	if page.Limit == 0 {
+		page.Limit = p.TopK
+	}
+	if page.Limit == 0 {
+		page.Limit = defaultPageLimit
+	}
+	if page.Limit < 0 || page.Limit > maxPageLimit {
+		return Page{}, fmt.Errorf("limit must be within [1, %d]", maxPageLimit)
+	}
+	if page.Offset < 0 {
+		return Page{}, errors.New("offset must not be negative")
+	}
+
+	if p.Cursor != "" {
+		offset, err := decodeCursor(p.Cursor)
+		if err != nil {
+			return Page{}, err
+		}
+		page.Offset = offset
+	}
+	// HNSW 最多返回 ef_search 条结果，更深的页无法取到
+	if page.Window() > vector.MaxEfSearch {
+		return Page{}, fmt.Errorf("offset + limit must be less than %d", vector.MaxEfSearch)
+	}
+	return page, nil
+}
+
+// Window 当前页需要的候选数量，多取一条用于判断是否还有下一页
+func (p Page) Window() int {
+	return p.Offset + p.Limit + 1
+}
+
+// pageResult 对已排序的结果切片，返回当前页以及下一页游标（没有下一页时为空）
+func pageResult[T any](items []T, page Page) ([]T, string) {
+	if page.Offset >= len(items) {
+		return []T{}, ""
+	}
+	end := page.Offset + page.Limit
+	if end >= len(items) {
+		return items[page.Offset:], ""
+	}
+	return items[page.Offset:end], encodeCursor(end)
+}
+
+// trimPage 用于已在 SQL 中按 OFFSET / LIMIT Limit+1 取出的结果
+func trimPage[T any](items []T, page Page) ([]T, string) {
+	if len(items) <= page.Limit {
+		return items, ""
+	}
+	return items[:page.Limit], encodeCursor(page.Offset + page.Limit)
+}
+
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, errors.New("invalid cursor")
+	}
+	offset, err := strconv.Atoi(string(raw))
+	if err != nil || offset < 0 {
+		return 0, errors.New("invalid cursor")
+	}
+	return offset, nil
+}
@@ -0,0 +1,34 @@
+package search
+
+import "testing"
+
+func TestPageParamsResolve(t *testing.T) {
+	cases := []struct {
+		name    string
+		params  PageParams
+		want    Page
+		wantErr bool
+	}{
+		{"defaults", PageParams{}, Page{Offset: 0, Limit: defaultPageLimit}, false},
+		{"legacy topK", PageParams{TopK: 20}, Page{Offset: 0, Limit: 20}, false},
+		{"limit over topK", PageParams{Limit: 5, TopK: 20}, Page{Offset: 0, Limit: 5}, false},
+		{"cursor over offset", PageParams{Limit: 10, Offset: 3, Cursor: encodeCursor(40)}, Page{Offset: 40, Limit: 10}, false},
+		{"deepest page", PageParams{Limit: 199, Offset: 800}, Page{Offset: 800, Limit: 199}, false},
+		{"beyond ef_search", PageParams{Limit: 200, Offset: 800}, Page{}, true},
+		{"cursor beyond ef_search", PageParams{Limit: 10, Cursor: encodeCursor(990)}, Page{}, true},
+		{"limit too large", PageParams{Limit: maxPageLimit + 1}, Page{}, true},
+		{"negative offset", PageParams{Offset: -1}, Page{}, true},
+		{"invalid cursor", PageParams{Cursor: "!"}, Page{}, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := c.params.Resolve()
+			if (err != nil) != c.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, c.wantErr)
+			}
+			if got != c.want {
+				t.Errorf("page = %+v, want %+v", got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package search
+
+import (
+	"ThinkBank-backend/internal/db"
+	"ThinkBank-backend/internal/vector"
+	"errors"
+	"time"
+
+	"github.com/pgvector/pgvector-go"
+	"gorm.io/gorm"
+)
+
+// RecallReport HNSW 召回率评估结果
+type RecallReport struct {
+	Samples           int                 `json:"samples"`
+	K                 int                 `json:"k"`
+	Params            vector.SearchParams `json:"params"`
+	Recall            float64             `json:"recall"`
+	MinRecall         float64             `json:"minRecall"`
+	AvgANNLatencyMs   float64             `json:"avgAnnLatencyMs"`
+	AvgExactLatencyMs float64             `json:"avgExactLatencyMs"`
+}
+
+// MeasureRecall 随机抽取已存储的向量作为查询，计算 HNSW 结果相对暴力检索的 recall@k
+func MeasureRecall(samples, k int, opts VectorOptions) (*RecallReport, error) {
+	var queries []struct {
+		Vector pgvector.Vector
+	}
+	err := db.Instance().Raw(`
+        SELECT vector FROM embeddings
+        WHERE `+vector.ActiveModel().Predicate()+`
+        ORDER BY random()
+        LIMIT ?
+    `, samples).Scan(&queries).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(queries) == 0 {
+		return nil, errors.New("no embeddings to benchmark")
+	}
+
+	report := &RecallReport{Samples: len(queries), K: k, Params: opts.HNSW, MinRecall: 1}
+	var annTotal, exactTotal time.Duration
+	for _, q := range queries {
+		start := time.Now()
+		approx, err := searchVectors(vectorQuery{Embedding: q.Vector.Slice(), Options: opts, Limit: k})
+		if err != nil {
+			return nil, err
+		}
+		annTotal += time.Since(start)
+
+		start = time.Now()
+		exact, err := exactVectors(q.Vector.Slice(), opts.MinSimilarity, k)
+		if err != nil {
+			return nil, err
+		}
+		exactTotal += time.Since(start)
+
+		recall := overlap(approx, exact)
+		report.Recall += recall
+		report.MinRecall = min(report.MinRecall, recall)
+	}
+
+	n := float64(len(queries))
+	report.Recall /= n
+	report.AvgANNLatencyMs = annTotal.Seconds() * 1000 / n
+	report.AvgExactLatencyMs = exactTotal.Seconds() * 1000 / n
+	return report, nil
+}
+
+// exactVectors 关闭索引扫描做暴力检索，作为召回率的基准
+func exactVectors(embedding []float32, minSimilarity float64, k int) ([]vectorHit, error) {
+	m := vector.ActiveModel()
+	vec := pgvector.NewVector(vector.Prepare(embedding))
+	op := vector.ActiveMetric().Operator()
+	where, whereArgs := vectorCondition(m, vec, minSimilarity, Filter{}, nil)
+	args := append([]interface{}{vec}, whereArgs...)
+	args = append(args, k)
+
+	var hits []vectorHit
+	err := db.Instance().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SET LOCAL enable_indexscan = off").Error; err != nil {
+			return err
+		}
+		return tx.Raw(`
+            SELECT file_id AS id, `+m.Column()+` `+op+` ? AS distance
+            FROM embeddings
+            WHERE `+where+`
+            ORDER BY distance, id
+            LIMIT ?
+        `, args...).Scan(&hits).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hits, nil
+}
+
+// overlap 计算 approx 中命中 exact 的比例
+func overlap(approx, exact []vectorHit) float64 {
+	if len(exact) == 0 {
+		return 1
+	}
+	truth := make(map[uint]struct{}, len(exact))
+	for _, h := range exact {
+		truth[h.ID] = struct{}{}
+	}
+	found := 0
+	for _, h := range approx {
+		if _, ok := truth[h.ID]; ok {
+			found++
+		}
+	}
+	return float64(found) / float64(len(exact))
+}
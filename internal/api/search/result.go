@@ -0,0 +1,65 @@
+package search
+
+import (
+	"ThinkBank-backend/internal/model"
+	"ThinkBank-backend/internal/vector"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ScoredFile 检索结果，附带分数、距离与两路原始分数
+type ScoredFile struct {
+	model.File
+	Score       float64  // 最终排序分数
+	Distance    *float64 // 向量距离，未被向量检索命中时为 nil
+	TextScore   *float64 // ts_rank 分数，未被文本检索命中时为 nil
+	VectorScore *float64 // 向量相似度，未被向量检索命中时为 nil
+}
+
+// distanceToSimilarity 按当前度量把距离转为相似度
+func distanceToSimilarity(distance float64) float64 {
+	return vector.ActiveMetric().Similarity(distance)
+}
+
+// similarityToDistance distanceToSimilarity 的反函数，用于把相似度阈值下推到 SQL
+func similarityToDistance(similarity float64) float64 {
+	return vector.ActiveMetric().Distance(similarity)
+}
+
+// toResultMap 统一的搜索结果 JSON
+func toResultMap(f ScoredFile) map[string]interface{} {
+	return map[string]interface{}{
+		"id":               f.ID,
+		"caption":          f.Caption,
+		"filename":         f.FileName,
+		"originalFilePath": f.OriginalFilePath,
+		"filePath":         f.FilePath,
+		"thumbnailUrl":     f.ThumbnailURL(),
+		"type":             f.Type,
+		"score":            f.Score,
+		"distance":         f.Distance,
+		"textScore":        f.TextScore,
+		"vectorScore":      f.VectorScore,
+	}
+}
+
+// pageResponse 统一的分页搜索响应
+func pageResponse(files []ScoredFile, page Page, nextCursor string, extra fiber.Map) fiber.Map {
+	result := make([]map[string]interface{}, len(files))
+	for i, f := range files {
+		result[i] = toResultMap(f)
+	}
+
+	resp := fiber.Map{
+		"count":      len(result),
+		"offset":     page.Offset,
+		"limit":      page.Limit,
+		"hasMore":    nextCursor != "",
+		"nextCursor": nextCursor,
+		"files":      result,
+	}
+	for k, v := range extra {
+		resp[k] = v
+	}
+	return resp
+}
@@ -1,16 +1,12 @@
 package search
 
 import (
-	"ThinkBank-backend/internal/db"
-	"ThinkBank-backend/internal/model"
 	"ThinkBank-backend/internal/service"
 	"crypto/rand"
 	"encoding/hex"
-	"fmt"
 	"log"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/pgvector/pgvector-go"
 )
 
 // 生成随机文件名（保留原文件后缀）
@@ -60,50 +56,29 @@ func RegisterSearchByImage(app fiber.Router, modelService service.ModelService,
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "cannot save temp file"})
 		}
 
-		topK := 10
-		if val := c.FormValue("topK"); val != "" {
-			if _, err := fmt.Sscanf(val, "%d", &topK); err != nil || topK <= 0 {
-				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid topK value"})
-			}
+		page, opts, err := parseFormSearchParams(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 		}
-
-		files, err := ByImage(tmpFilePath, modelService, topK)
+		filter, err := formFilter(c)
 		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
 		}
 
-		result := make([]map[string]interface{}, len(files))
-		for i, f := range files {
-			result[i] = map[string]interface{}{
-				"caption":          f.Caption,
-				"filename":         f.FileName,
-				"originalFilePath": f.OriginalFilePath,
-				"filePath":         f.FilePath,
-				"type":             f.Type,
-			}
+		files, nextCursor, err := ByImage(tmpFilePath, modelService, page, opts, filter)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
 		}
 
-		return c.JSON(fiber.Map{"count": len(result), "files": result})
+		return c.JSON(pageResponse(files, page, nextCursor, nil))
 	})
 }
 
 // ByImage 使用 embedding + HNSW 索引直接搜索
-func ByImage(imagePath string, modelService service.ModelService, topK int) ([]model.File, error) {
+func ByImage(imagePath string, modelService service.ModelService, page Page, opts VectorOptions, filter Filter) ([]ScoredFile, string, error) {
 	_, embedding, err := modelService.AnalyzeImage(imagePath)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
-
-	var files []model.File
-	err = db.Instance().Raw(`
-        SELECT *, vector <-> ? AS distance
-        FROM files
-        ORDER BY vector <-> ? 
-        LIMIT ?
-    `, pgvector.NewVector(embedding), pgvector.NewVector(embedding), topK).Scan(&files).Error
-	if err != nil {
-		return nil, err
-	}
-
-	return files, nil
+	return byVector(embedding, page, opts, filter)
 }
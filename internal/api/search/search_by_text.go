@@ -2,20 +2,23 @@ package search
 
 import (
 	"ThinkBank-backend/internal/db"
-	"ThinkBank-backend/internal/model"
 	"ThinkBank-backend/internal/service"
-	"sort"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/pgvector/pgvector-go"
 )
 
 // RegisterSearchByText 注册 /text/search 路由
 func RegisterSearchByText(app fiber.Router, modelService service.ModelService) {
 	app.Post("/text/search", func(c *fiber.Ctx) error {
 		var req struct {
-			Query string `json:"query"`
-			TopK  int    `json:"topK"`
+			PageParams
+			VectorParams
+			Query  string   `json:"query"`
+			Fusion string   `json:"fusion"` // weighted / minmax / rrf
+			Alpha  *float64 `json:"alpha"`
+			RRFK   int      `json:"rrfK"`
+			Filter []string `json:"filter"` // 例如 ["camera=iPhone 15", "iso>=400"]
+			Facets bool     `json:"facets"` // 是否返回候选集的分面统计
 		}
 
 		if err := c.BodyParser(&req); err != nil {
@@ -24,150 +27,171 @@ func RegisterSearchByText(app fiber.Router, modelService service.ModelService) {
 			})
 		}
 
-		if req.TopK == 0 {
-			req.TopK = 10
+		page, err := req.PageParams.Resolve()
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
 		}
 
-		files, err := ByText(req.Query, modelService, req.TopK, 0.5)
+		vectorOpts, err := req.VectorParams.Resolve()
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		opts := DefaultFusionOptions()
+		if req.Fusion != "" {
+			opts.Method = FusionMethod(req.Fusion)
+		}
+		if req.Alpha != nil {
+			opts.Alpha = *req.Alpha
+		}
+		if req.RRFK != 0 {
+			opts.RRFK = req.RRFK
+		}
+		if err := opts.Validate(); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		filter, err := ParseFilter(req.Filter)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		files, nextCursor, candidates, err := byText(req.Query, modelService, page, vectorOpts, opts, filter)
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"error": err.Error(),
 			})
 		}
 
-		result := make([]map[string]interface{}, len(files))
-		for i, f := range files {
-			result[i] = map[string]interface{}{
-				"caption":          f.Caption,
-				"filename":         f.FileName,
-				"originalFilePath": f.OriginalFilePath,
-				"filePath":         f.FilePath,
-				"type":             f.Type,
+		extra := fiber.Map{"fusion": opts.Method}
+		if req.Facets {
+			facets, err := ComputeFacets(filter, candidates)
+			if err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": err.Error(),
+				})
 			}
+			extra["facets"] = facets
 		}
-
-		return c.JSON(fiber.Map{
-			"count": len(result),
-			"files": result,
-		})
+		return c.JSON(pageResponse(files, page, nextCursor, extra))
 	})
 }
 
-func topKText(query string, topK int) (map[uint]float64, error) {
-	var results []struct {
-		ID    uint
-		Score float64
-	}
-	err := db.Instance().Raw(`
-        SELECT id, ts_rank(tsv, websearch_to_tsquery('english', ?)) AS score
-        FROM files
-        WHERE tsv @@ websearch_to_tsquery('english', ?)
-        ORDER BY score DESC
-        LIMIT ?
-    `, query, query, topK).Scan(&results).Error
-	if err != nil {
-		return nil, err
-	}
-
-	scores := make(map[uint]float64)
-	for _, r := range results {
-		scores[r.ID] = r.Score
-	}
-	return scores, nil
-}
+// topKText 全文检索，同时匹配拍摄位置的国家/地区/城市。已删除的文件在排名前排除，
+// 否则分页后被 loadFiles 丢弃，导致页面不满且游标跳过结果
+func topKText(query string, topK int, filter Filter) ([]rankedID, error) {
+	where, whereArgs := filter.Where()
+	args := append([]interface{}{query}, whereArgs...)
+	args = append(args, topK)
 
-// -------------------- 向量搜索 --------------------
-func topKVector(embedding []float32, topK int) (map[uint]float64, error) {
-	var results []struct {
-		ID       uint
-		Distance float64
-	}
+	// 两路分别走各自的 GIN 索引，再按合并后的文档计算分数
+	var results []rankedID
 	err := db.Instance().Raw(`
-        SELECT id, vector <-> ? AS distance
-        FROM files
-        ORDER BY vector <-> ?
+        WITH q AS (
+            SELECT websearch_to_tsquery('english', ?) AS query
+        ),
+        matched AS (
+            SELECT files.id FROM files, q WHERE files.tsv @@ q.query
+            UNION
+            SELECT geos.id FROM geos, q WHERE geos.place_tsv @@ q.query
+        )
+        SELECT files.id,
+               ts_rank(COALESCE(files.tsv, ''::tsvector) || COALESCE(geos.place_tsv, ''::tsvector), q.query) AS score
+        FROM matched
+        JOIN files ON files.id = matched.id
+        LEFT JOIN geos ON geos.id = files.id
+        CROSS JOIN q
+        WHERE files.deleted_at IS NULL AND `+where+`
+        ORDER BY score DESC, files.id
         LIMIT ?
-    `, pgvector.NewVector(embedding), pgvector.NewVector(embedding), topK).Scan(&results).Error
+    `, args...).Scan(&results).Error
 	if err != nil {
 		return nil, err
 	}
+	return results, nil
+}
 
-	scores := make(map[uint]float64)
-	for _, r := range results {
-		scores[r.ID] = 1 / (1 + r.Distance) // 距离转相似度
-	}
-	return scores, nil
+// ByText 文本 + 向量混合检索，返回当前页以及下一页游标
+func ByText(query string, modelService service.ModelService, page Page, vectorOpts VectorOptions, opts FusionOptions, filter Filter) ([]ScoredFile, string, error) {
+	files, nextCursor, _, err := byText(query, modelService, page, vectorOpts, opts, filter)
+	return files, nextCursor, err
 }
 
-func ByText(query string, modelService service.ModelService, topK int, alpha float64) ([]model.File, error) {
+// byText 同 ByText，额外返回两路检索的全部候选 ID，用于分面统计
+func byText(query string, modelService service.ModelService, page Page, vectorOpts VectorOptions, opts FusionOptions, filter Filter) ([]ScoredFile, string, []uint, error) {
 	// 1. 生成 embedding
 	embedding, err := modelService.AnalyzeText(query)
 	if err != nil {
-		return nil, err
+		return nil, "", nil, err
 	}
 
-	// 2. 文本搜索 topK
-	textScores, err := topKText(query, topK)
+	// 2. 文本搜索，两路都取到当前页末尾为止的候选
+	textResults, err := topKText(query, page.Window(), filter)
 	if err != nil {
-		return nil, err
+		return nil, "", nil, err
 	}
 
-	// 3. 向量搜索 topK
-	vectorScores, err := topKVector(embedding, topK)
+	// 3. 向量搜索
+	hits, err := searchVectors(vectorQuery{
+		Embedding: embedding,
+		Options:   vectorOpts,
+		Filter:    filter,
+		Limit:     page.Window(),
+	})
 	if err != nil {
-		return nil, err
-	}
-
-	// 4. 融合分数
-	finalScores := make(map[uint]float64)
-	for id, vScore := range vectorScores {
-		tScore := textScores[id]
-		finalScores[id] = alpha*tScore + (1-alpha)*vScore
+		return nil, "", nil, err
 	}
-	for id, tScore := range textScores {
-		if _, ok := finalScores[id]; !ok {
-			finalScores[id] = alpha * tScore
-		}
+	distances := make(map[uint]float64, len(hits))
+	vectorResults := make([]rankedID, len(hits))
+	for i, h := range hits {
+		distances[h.ID] = h.Distance
+		vectorResults[i] = rankedID{ID: h.ID, Score: distanceToSimilarity(h.Distance)}
 	}
 
-	// 5. 排序取前 topK
-	type sf struct {
-		ID    uint
-		Score float64
-	}
-	var scoredList []sf
-	for id, score := range finalScores {
-		scoredList = append(scoredList, sf{ID: id, Score: score})
-	}
-	sort.Slice(scoredList, func(i, j int) bool {
-		return scoredList[i].Score > scoredList[j].Score
-	})
-	if len(scoredList) > topK {
-		scoredList = scoredList[:topK]
+	// 4. 融合分数并分页
+	fused := fuse(textResults, vectorResults, opts)
+	candidates := make([]uint, len(fused))
+	for i, s := range fused {
+		candidates[i] = s.ID
 	}
+	scoredList, nextCursor := pageResult(fused, page)
 
-	// 6. 查询文件信息
-	var files []model.File
-	var ids []uint
-	for _, s := range scoredList {
-		ids = append(ids, s.ID)
+	// 5. 查询文件信息
+	ids := make([]uint, len(scoredList))
+	for i, s := range scoredList {
+		ids[i] = s.ID
 	}
-	err = db.Instance().Where("id IN ?", ids).Find(&files).Error
+	idToFile, err := loadFiles(ids)
 	if err != nil {
-		return nil, err
+		return nil, "", nil, err
 	}
 
 	// 保持顺序一致
-	idToFile := make(map[uint]model.File)
-	for _, f := range files {
-		idToFile[f.ID] = f
-	}
-	ordered := make([]model.File, 0, len(ids))
-	for _, id := range ids {
-		if f, ok := idToFile[id]; ok {
-			ordered = append(ordered, f)
+	ordered := make([]ScoredFile, 0, len(ids))
+	for _, s := range scoredList {
+		f, ok := idToFile[s.ID]
+		if !ok {
+			continue
+		}
+		result := ScoredFile{
+			File:        f,
+			Score:       s.Score,
+			TextScore:   s.TextScore,
+			VectorScore: s.VectorScore,
+		}
+		if d, ok := distances[s.ID]; ok {
+			result.Distance = &d
 		}
+		ordered = append(ordered, result)
 	}
 
-	return ordered, nil
+	return ordered, nextCursor, candidates, nil
 }
@@ -0,0 +1,68 @@
+package search
+
+import (
+	"ThinkBank-backend/internal/db"
+	"ThinkBank-backend/internal/model"
+	"ThinkBank-backend/internal/vector"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// RegisterSearchSimilar 注册 /files/:id/similar 路由
+func RegisterSearchSimilar(app fiber.Router) {
+	app.Get("/files/:id/similar", func(c *fiber.Ctx) error {
+		id, err := strconv.ParseUint(c.Params("id"), 10, 64)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid file id"})
+		}
+
+		page, opts, err := parseFormSearchParams(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		filter, err := formFilter(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		files, nextCursor, err := BySimilarFile(uint(id), page, opts, filter)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "file not found"})
+		}
+		if errors.Is(err, ErrNoEmbedding) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		return c.JSON(pageResponse(files, page, nextCursor, fiber.Map{"sourceId": id}))
+	})
+}
+
+// ErrNoEmbedding 文件尚未生成 embedding
+var ErrNoEmbedding = errors.New("file has no embedding yet")
+
+// BySimilarFile 复用已存储的向量检索相似文件，结果中不包含该文件本身
+func BySimilarFile(id uint, page Page, opts VectorOptions, filter Filter) ([]ScoredFile, string, error) {
+	var source model.File
+	if err := db.Instance().Select("id").First(&source, id).Error; err != nil {
+		return nil, "", err
+	}
+
+	m := vector.ActiveModel()
+	var embedding model.Embedding
+	err := db.Instance().
+		Where("file_id = ? AND model_name = ? AND model_version = ?", id, m.Name, m.Version).
+		First(&embedding).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, "", ErrNoEmbedding
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return byVector(embedding.Vector.Slice(), page, opts, filter, id)
+}
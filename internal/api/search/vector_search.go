@@ -0,0 +1,236 @@
+package search
+
+import (
+	"ThinkBank-backend/internal/db"
+	"ThinkBank-backend/internal/model"
+	"ThinkBank-backend/internal/vector"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pgvector/pgvector-go"
+	"gorm.io/gorm"
+)
+
+// VectorParams 客户端传入的向量检索参数
+type VectorParams struct {
+	MinSimilarity float64 `json:"minSimilarity"`
+	EfSearch      int     `json:"efSearch"`
+	IterativeScan string  `json:"iterativeScan"`
+	MaxScanTuples int     `json:"maxScanTuples"`
+	Rerank        int     `json:"rerank"`
+}
+
+// VectorOptions 校验后的向量检索参数
+type VectorOptions struct {
+	MinSimilarity float64
+	HNSW          vector.SearchParams
+}
+
+// Resolve 校验参数，未指定的 HNSW 参数使用配置默认值
+func (p VectorParams) Resolve() (VectorOptions, error) {
+	if p.MinSimilarity < 0 || p.MinSimilarity > 1 {
+		return VectorOptions{}, errors.New("minSimilarity must be within [0, 1]")
+	}
+
+	hnsw := vector.DefaultSearchParams()
+	if p.EfSearch != 0 {
+		hnsw.EfSearch = p.EfSearch
+	}
+	if p.IterativeScan != "" {
+		hnsw.IterativeScan = p.IterativeScan
+	}
+	if p.MaxScanTuples != 0 {
+		hnsw.MaxScanTuples = p.MaxScanTuples
+	}
+	hnsw.Rerank = p.Rerank
+	if err := hnsw.Validate(); err != nil {
+		return VectorOptions{}, err
+	}
+
+	return VectorOptions{MinSimilarity: p.MinSimilarity, HNSW: hnsw}, nil
+}
+
+// parseFormSearchParams 从表单或 query string 解析分页与向量检索参数
+func parseFormSearchParams(c *fiber.Ctx) (Page, VectorOptions, error) {
+	var params PageParams
+	var vp VectorParams
+	for name, dst := range map[string]*int{
+		"limit":         &params.Limit,
+		"topK":          &params.TopK,
+		"offset":        &params.Offset,
+		"efSearch":      &vp.EfSearch,
+		"maxScanTuples": &vp.MaxScanTuples,
+		"rerank":        &vp.Rerank,
+	} {
+		if val := c.FormValue(name); val != "" {
+			n, err := strconv.Atoi(val)
+			if err != nil {
+				return Page{}, VectorOptions{}, fmt.Errorf("invalid %s value", name)
+			}
+			*dst = n
+		}
+	}
+	params.Cursor = c.FormValue("cursor")
+	vp.IterativeScan = c.FormValue("iterativeScan")
+
+	if val := c.FormValue("minSimilarity"); val != "" {
+		f, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			return Page{}, VectorOptions{}, errors.New("invalid minSimilarity value")
+		}
+		vp.MinSimilarity = f
+	}
+
+	page, err := params.Resolve()
+	if err != nil {
+		return Page{}, VectorOptions{}, err
+	}
+	opts, err := vp.Resolve()
+	if err != nil {
+		return Page{}, VectorOptions{}, err
+	}
+	return page, opts, nil
+}
+
+// vectorHit 向量检索命中
+type vectorHit struct {
+	ID       uint
+	Distance float64
+}
+
+// vectorQuery 一次向量检索
+type vectorQuery struct {
+	Embedding  []float32
+	Options    VectorOptions
+	Filter     Filter
+	ExcludeIDs []uint
+	Limit      int
+	Offset     int
+}
+
+// vectorCondition 向量检索的公共 WHERE 条件，只检索当前模型中满足过滤条件的文件，excludeIDs 中的文件不参与检索
+func vectorCondition(m vector.Model, vec pgvector.Vector, minSimilarity float64, filter Filter, excludeIDs []uint) (string, []interface{}) {
+	where := m.Predicate()
+	var args []interface{}
+	if !filter.Empty() {
+		cond, condArgs := filter.Where()
+		where += " AND file_id IN (SELECT id FROM files WHERE deleted_at IS NULL AND " + cond + ")"
+		args = append(args, condArgs...)
+	}
+	if len(excludeIDs) > 0 {
+		where += " AND file_id NOT IN ?"
+		args = append(args, excludeIDs)
+	}
+	if minSimilarity > 0 {
+		where += " AND " + m.Column() + " " + vector.ActiveMetric().Operator() + " ? <= ?"
+		args = append(args, vec, similarityToDistance(minSimilarity))
+	}
+	return where, args
+}
+
+// searchVectors 在事务内设置 HNSW 参数后检索，取出候选集后按距离排序、分页
+func searchVectors(q vectorQuery) ([]vectorHit, error) {
+	m := vector.ActiveModel()
+	if err := m.Check(q.Embedding); err != nil {
+		return nil, err
+	}
+
+	vec := pgvector.NewVector(vector.Prepare(q.Embedding))
+	op := vector.ActiveMetric().Operator()
+	where, whereArgs := vectorCondition(m, vec, q.Options.MinSimilarity, q.Filter, q.ExcludeIDs)
+
+	candidates := q.Options.HNSW.Candidates(q.Offset + q.Limit)
+	hnsw := q.Options.HNSW.
+		ForFilter(!q.Filter.Empty() || len(q.ExcludeIDs) > 0 || q.Options.MinSimilarity > 0).
+		ForCandidates(candidates)
+
+	args := append([]interface{}{vec}, whereArgs...)
+	args = append(args, vec, candidates, q.Limit, q.Offset)
+
+	var hits []vectorHit
+	err := db.Instance().Transaction(func(tx *gorm.DB) error {
+		if err := hnsw.Apply(tx); err != nil {
+			return err
+		}
+		// 索引与外层使用同一个全精度距离，外层排序只在 relaxed_order 下改变结果：
+		// 此时索引返回的顺序不保证严格。其余情况下 rerank 只是多取候选，提高召回
+		return tx.Raw(`
+            WITH candidates AS MATERIALIZED (
+                SELECT file_id AS id, `+m.Column()+` `+op+` ? AS distance
+                FROM embeddings
+                WHERE `+where+`
+                ORDER BY `+m.Column()+` `+op+` ?
+                LIMIT ?
+            )
+            SELECT id, distance
+            FROM candidates
+            ORDER BY distance, id
+            LIMIT ? OFFSET ?
+        `, args...).Scan(&hits).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hits, nil
+}
+
+// loadFiles 按 ID 批量读取文件
+func loadFiles(ids []uint) (map[uint]model.File, error) {
+	idToFile := make(map[uint]model.File, len(ids))
+	if len(ids) == 0 {
+		return idToFile, nil
+	}
+
+	var files []model.File
+	if err := db.Instance().Where("id IN ?", ids).Find(&files).Error; err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		idToFile[f.ID] = f
+	}
+	return idToFile, nil
+}
+
+// byVector 按向量距离排序分页检索
+func byVector(embedding []float32, page Page, opts VectorOptions, filter Filter, excludeIDs ...uint) ([]ScoredFile, string, error) {
+	hits, err := searchVectors(vectorQuery{
+		Embedding:  embedding,
+		Options:    opts,
+		Filter:     filter,
+		ExcludeIDs: excludeIDs,
+		Limit:      page.Limit + 1,
+		Offset:     page.Offset,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	hits, nextCursor := trimPage(hits, page)
+	ids := make([]uint, len(hits))
+	for i, h := range hits {
+		ids[i] = h.ID
+	}
+	idToFile, err := loadFiles(ids)
+	if err != nil {
+		return nil, "", err
+	}
+
+	files := make([]ScoredFile, 0, len(hits))
+	for _, h := range hits {
+		f, ok := idToFile[h.ID]
+		if !ok {
+			continue
+		}
+		distance := h.Distance
+		similarity := distanceToSimilarity(distance)
+		files = append(files, ScoredFile{
+			File:        f,
+			Score:       similarity,
+			Distance:    &distance,
+			VectorScore: &similarity,
+		})
+	}
+	return files, nextCursor, nil
+}
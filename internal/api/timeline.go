@@ -0,0 +1,251 @@
+package api
+
+import (
+	"ThinkBank-backend/internal/api/search"
+	"ThinkBank-backend/internal/db"
+	"ThinkBank-backend/internal/model"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// timelineTakenExpr 拍摄时间：EXIF，其次为位置记录的时间（导入 sidecar），最后为上传时间
+const timelineTakenExpr = `COALESCE(files.taken_at, geos.create_at, files.created_at)`
+
+const (
+	defaultOnThisDayLimit = 100
+	maxOnThisDayLimit     = 1000
+)
+
+// timelineFormats 各粒度的时间段标签格式（to_char）。标签与分组都在数据库会话时区内计算，
+// 在 Go 中按服务器时区格式化可能与分组差一天
+var timelineFormats = map[string]string{
+	"year":  "YYYY",
+	"month": "YYYY-MM",
+	"day":   "YYYY-MM-DD",
+}
+
+// TimelineBucket 时间轴上的一个时间段
+type TimelineBucket struct {
+	Period   string    `json:"period"` // 2024 / 2024-05 / 2024-05-01
+	Start    time.Time `json:"start"`
+	Count    int       `json:"count"`
+	CoverID  uint      `json:"coverId"` // 时间段内最近拍摄的图片
+	CoverURL string    `json:"coverUrl"`
+}
+
+// TimelineFile 时间轴中的文件
+type TimelineFile struct {
+	ID           uint      `json:"id"`
+	FileName     string    `json:"fileName"`
+	Type         string    `json:"type"`
+	ThumbnailURL string    `json:"thumbnailUrl"`
+	Caption      string    `json:"caption"`
+	TakenAt      time.Time `json:"takenAt"`
+}
+
+// OnThisDayGroup 往年同一天的文件
+type OnThisDayGroup struct {
+	Year     int            `json:"year"`
+	YearsAgo int            `json:"yearsAgo"`
+	Files    []TimelineFile `json:"files"`
+}
+
+// RegisterTimelineRoutes 注册时间轴接口：
+// /timeline?granularity=year|month|day&from=&to= 按拍摄时间统计数量与封面，
+// /timeline/on-this-day?date= 返回往年同一天拍摄的文件。两者都支持元数据过滤
+func RegisterTimelineRoutes(app fiber.Router) {
+	app.Get("/timeline", func(c *fiber.Ctx) error {
+		granularity := c.Query("granularity", "month")
+		if _, ok := timelineFormats[granularity]; !ok {
+			return c.Status(400).JSON(fiber.Map{"error": "granularity must be year, month or day"})
+		}
+		var from, to *time.Time
+		for name, dst := range map[string]**time.Time{"from": &from, "to": &to} {
+			if val := c.Query(name); val != "" {
+				t, err := parseTripTime(val)
+				if err != nil {
+					return c.Status(400).JSON(fiber.Map{"error": fmt.Sprintf("invalid %s value", name)})
+				}
+				*dst = &t
+			}
+		}
+		filter, err := search.FilterFromQuery(c)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		buckets, err := QueryTimeline(granularity, from, to, filter)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(fiber.Map{"granularity": granularity, "buckets": buckets})
+	})
+
+	app.Get("/timeline/on-this-day", func(c *fiber.Ctx) error {
+		date := time.Now()
+		if val := c.Query("date"); val != "" {
+			t, err := time.ParseInLocation("2006-01-02", val, time.Local)
+			if err != nil {
+				return c.Status(400).JSON(fiber.Map{"error": "date must be YYYY-MM-DD"})
+			}
+			date = t
+		}
+		limit := c.QueryInt("limit", defaultOnThisDayLimit)
+		if limit < 1 || limit > maxOnThisDayLimit {
+			return c.Status(400).JSON(fiber.Map{"error": fmt.Sprintf("limit must be within [1, %d]", maxOnThisDayLimit)})
+		}
+		filter, err := search.FilterFromQuery(c)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		groups, err := QueryOnThisDay(date, limit, filter)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(fiber.Map{"date": date.Format("2006-01-02"), "years": groups})
+	})
+}
+
+// QueryTimeline 按粒度统计各时间段的文件数，从新到旧排列，to 不含
+func QueryTimeline(granularity string, from, to *time.Time, filter search.Filter) ([]TimelineBucket, error) {
+	where, whereArgs := filter.Where()
+	cond := "files.deleted_at IS NULL AND " + where
+	args := append([]interface{}{}, whereArgs...)
+	if from != nil {
+		cond += " AND " + timelineTakenExpr + " >= ?"
+		args = append(args, *from)
+	}
+	if to != nil {
+		cond += " AND " + timelineTakenExpr + " < ?"
+		args = append(args, *to)
+	}
+
+	// 粒度来自固定表，可以直接拼入
+	var rows []struct {
+		Period  string
+		Start   time.Time
+		Count   int
+		CoverID uint
+	}
+	err := db.Instance().Raw(fmt.Sprintf(`
+        WITH t AS (
+            SELECT files.id, files.type, %[2]s AS taken
+            FROM files
+            LEFT JOIN geos ON geos.id = files.id
+            WHERE %[3]s
+        )
+        SELECT to_char(date_trunc('%[1]s', taken), '%[4]s') AS period,
+               date_trunc('%[1]s', taken) AS start,
+               COUNT(*) AS count,
+               (array_agg(id ORDER BY type = 'image' DESC, taken DESC))[1] AS cover_id
+        FROM t
+        GROUP BY 2
+        ORDER BY 2 DESC
+    `, granularity, timelineTakenExpr, cond, timelineFormats[granularity]), args...).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, len(rows))
+	for i, r := range rows {
+		ids[i] = r.CoverID
+	}
+	covers, err := loadFileMap(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	buckets := make([]TimelineBucket, len(rows))
+	for i, r := range rows {
+		cover := covers[r.CoverID]
+		buckets[i] = TimelineBucket{
+			Period:   r.Period,
+			Start:    r.Start,
+			Count:    r.Count,
+			CoverID:  r.CoverID,
+			CoverURL: cover.ThumbnailURL(),
+		}
+	}
+	return buckets, nil
+}
+
+// QueryOnThisDay 往年与 date 同月同日拍摄的文件，按年份从近到远分组。
+// 月、日与年份都在数据库会话时区内取出，保证分组与筛选一致
+func QueryOnThisDay(date time.Time, limit int, filter search.Filter) ([]OnThisDayGroup, error) {
+	where, whereArgs := filter.Where()
+	args := append([]interface{}{}, whereArgs...)
+	args = append(args, int(date.Month()), date.Day(), date.Year(), limit)
+
+	var rows []struct {
+		ID    uint
+		Taken time.Time
+		Year  int
+	}
+	err := db.Instance().Raw(`
+        WITH t AS (
+            SELECT files.id, `+timelineTakenExpr+` AS taken
+            FROM files
+            LEFT JOIN geos ON geos.id = files.id
+            WHERE files.deleted_at IS NULL AND `+where+`
+        )
+        SELECT id, taken, EXTRACT(YEAR FROM taken)::int AS year
+        FROM t
+        WHERE EXTRACT(MONTH FROM taken) = ?
+          AND EXTRACT(DAY FROM taken) = ?
+          AND EXTRACT(YEAR FROM taken) < ?
+        ORDER BY taken DESC
+        LIMIT ?
+    `, args...).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, len(rows))
+	for i, r := range rows {
+		ids[i] = r.ID
+	}
+	files, err := loadFileMap(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []OnThisDayGroup
+	for _, r := range rows {
+		f, ok := files[r.ID]
+		if !ok {
+			continue
+		}
+		if len(groups) == 0 || groups[len(groups)-1].Year != r.Year {
+			groups = append(groups, OnThisDayGroup{Year: r.Year, YearsAgo: date.Year() - r.Year})
+		}
+		g := &groups[len(groups)-1]
+		g.Files = append(g.Files, TimelineFile{
+			ID:           f.ID,
+			FileName:     f.FileName,
+			Type:         f.Type,
+			ThumbnailURL: f.ThumbnailURL(),
+			Caption:      f.Caption,
+			TakenAt:      r.Taken,
+		})
+	}
+	return groups, nil
+}
+
+// loadFileMap 按 ID 批量读取文件
+func loadFileMap(ids []uint) (map[uint]model.File, error) {
+	idToFile := make(map[uint]model.File, len(ids))
+	if len(ids) == 0 {
+		return idToFile, nil
+	}
+	var files []model.File
+	if err := db.Instance().Where("id IN ?", ids).Find(&files).Error; err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		idToFile[f.ID] = f
+	}
+	return idToFile, nil
+}
@@ -2,6 +2,12 @@ package api
 
 import (
 	"ThinkBank-backend/internal/db"
+	"ThinkBank-backend/internal/trip"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -9,6 +15,7 @@ import (
 )
 
 type TripCluster struct {
+	ID         uint          `json:"id,omitempty"` // 预计算的行程 ID，用于 /trip/:id；自定义半径时为空
 	Level      string        `json:"level"`
 	Period     time.Time     `json:"period"`
 	CenterLon  float64       `json:"center_lon"`
@@ -17,60 +24,286 @@ type TripCluster struct {
 	StartTs    time.Time     `json:"start_ts"`
 	EndTs      time.Time     `json:"end_ts"`
 	PhotoIDs   pq.Int64Array `json:"photo_ids" gorm:"type:integer[]"`
+	Country    *string       `json:"country"` // 簇内照片最常见的地名
+	Region     *string       `json:"region"`
+	City       *string       `json:"city"`
 }
 
-// RegisterTripRoutes 注册上传路由
+const (
+	defaultTripMinPhotos = 5
+	minTripRadius        = 100
+	maxTripRadius        = 5000000
+	maxTripMinPhotos     = 10000
+)
+
+// TripParams 行程检测参数
+type TripParams struct {
+	Levels    []string           // 为空时计算全部层级
+	Radius    float64            // 聚类半径（米），0 表示使用各层级默认值
+	MinPhotos int                // 每个行程至少包含的照片数
+	From      *time.Time         // 拍摄时间下界（含）
+	To        *time.Time         // 拍摄时间上界（不含）
+	BBox      *[4]float64        // minLon, minLat, maxLon, maxLat
+	radii     map[string]float64 // 校验后各层级实际使用的半径
+}
+
+// parseTripParams 从 query string 解析并校验参数
+func parseTripParams(c *fiber.Ctx) (TripParams, error) {
+	p := TripParams{MinPhotos: defaultTripMinPhotos}
+
+	if val := c.Query("level"); val != "" {
+		p.Levels = strings.Split(val, ",")
+	}
+	if val := c.Query("radius"); val != "" {
+		r, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			return TripParams{}, errors.New("invalid radius value")
+		}
+		p.Radius = r
+	}
+	if val := c.Query("minPhotos"); val != "" {
+		n, err := strconv.Atoi(val)
+		if err != nil {
+			return TripParams{}, errors.New("invalid minPhotos value")
+		}
+		p.MinPhotos = n
+	}
+	for name, dst := range map[string]**time.Time{"from": &p.From, "to": &p.To} {
+		if val := c.Query(name); val != "" {
+			t, err := parseTripTime(val)
+			if err != nil {
+				return TripParams{}, fmt.Errorf("invalid %s value", name)
+			}
+			*dst = &t
+		}
+	}
+	if val := c.Query("bbox"); val != "" {
+		bbox, err := parseBBox(val)
+		if err != nil {
+			return TripParams{}, err
+		}
+		p.BBox = &bbox
+	}
+
+	return p, p.Validate()
+}
+
+// parseBBox 解析并校验 minLon,minLat,maxLon,maxLat
+func parseBBox(val string) ([4]float64, error) {
+	var bbox [4]float64
+	parts := strings.Split(val, ",")
+	if len(parts) != 4 {
+		return bbox, errors.New("bbox must be minLon,minLat,maxLon,maxLat")
+	}
+	for i, part := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return bbox, errors.New("invalid bbox value")
+		}
+		bbox[i] = f
+	}
+	return bbox, validateBBox(bbox)
+}
+
+func validateBBox(b [4]float64) error {
+	if b[0] < -180 || b[2] > 180 || b[1] < -90 || b[3] > 90 || b[0] >= b[2] || b[1] >= b[3] {
+		return errors.New("bbox must be minLon,minLat,maxLon,maxLat within valid ranges")
+	}
+	return nil
+}
+
+// parseTripTime 支持 2006-01-02 与 RFC3339
+func parseTripTime(val string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", val, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, val)
+}
+
+// Validate 校验参数并确定各层级的聚类半径
+func (p *TripParams) Validate() error {
+	if p.Radius != 0 && (p.Radius < minTripRadius || p.Radius > maxTripRadius) {
+		return fmt.Errorf("radius must be within [%d, %d] meters", minTripRadius, maxTripRadius)
+	}
+	if p.MinPhotos < 1 || p.MinPhotos > maxTripMinPhotos {
+		return fmt.Errorf("minPhotos must be within [1, %d]", maxTripMinPhotos)
+	}
+	if p.From != nil && p.To != nil && !p.From.Before(*p.To) {
+		return errors.New("from must be before to")
+	}
+	if p.BBox != nil {
+		if err := validateBBox(*p.BBox); err != nil {
+			return err
+		}
+	}
+
+	p.radii = make(map[string]float64)
+	for _, level := range trip.Levels {
+		if len(p.Levels) == 0 || slices.Contains(p.Levels, level.Name) {
+			p.radii[level.Name] = level.Radius
+			if p.Radius != 0 {
+				p.radii[level.Name] = p.Radius
+			}
+		}
+	}
+	for _, name := range p.Levels {
+		if _, ok := p.radii[name]; !ok {
+			return fmt.Errorf("unknown level: %s", name)
+		}
+	}
+	return nil
+}
+
+// RegisterTripRoutes 注册行程路由 /trip?level=day,week&radius=&minPhotos=&from=&to=&bbox= 与详情 /trip/:id
 func RegisterTripRoutes(app fiber.Router) {
 	app.Get("/trip", func(c *fiber.Ctx) error {
-		clusters, err := QueryTrips()
+		params, err := parseTripParams(c)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		// 默认半径的结果已预先计算，自定义半径时实时聚类
+		query := QueryStoredTrips
+		if !params.DefaultRadii() {
+			query = QueryTrips
+		}
+		clusters, err := query(params)
 		if err != nil {
 			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 		}
 		return c.JSON(clusters)
 	})
+
+	app.Get("/trip/:id", func(c *fiber.Ctx) error {
+		id, err := c.ParamsInt("id")
+		if err != nil || id <= 0 {
+			return c.Status(400).JSON(fiber.Map{"error": "invalid id"})
+		}
+		detail, err := QueryTripDetail(uint(id))
+		if errors.Is(err, errTripNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+		} else if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(detail)
+	})
 }
 
-func QueryTrips() ([]TripCluster, error) {
-	sql := `
-WITH params AS (
-  SELECT
-    20000.0::double precision AS eps_day,
-    150000.0::double precision AS eps_week,
-    800000.0::double precision AS eps_month
-),
+// DefaultRadii 各层级是否都使用默认半径
+func (p TripParams) DefaultRadii() bool {
+	for name, radius := range p.radii {
+		if def, _ := trip.DefaultRadius(name); radius != def {
+			return false
+		}
+	}
+	return true
+}
 
-clusters_day AS (
-  SELECT 
-    date_trunc('day', create_at)::date AS period,
-    unnest(ST_ClusterWithin(geom3857, params.eps_day)) AS cluster_geom_3857
-  FROM geos, params
-  GROUP BY date_trunc('day', create_at)
-),
+// tripFilter 时间与范围条件，alias 为 geos 的表别名
+func (p TripParams) tripFilter(alias string) (string, []interface{}) {
+	where := alias + ".geom3857 IS NOT NULL"
+	var args []interface{}
+	if p.From != nil {
+		where += " AND " + alias + ".create_at >= ?"
+		args = append(args, *p.From)
+	}
+	if p.To != nil {
+		where += " AND " + alias + ".create_at < ?"
+		args = append(args, *p.To)
+	}
+	if b := p.BBox; b != nil {
+		where += " AND " + alias + ".geom && ST_MakeEnvelope(?, ?, ?, ?, 4326)"
+		args = append(args, b[0], b[1], b[2], b[3])
+	}
+	return where, args
+}
 
-clusters_week AS (
-  SELECT 
-    date_trunc('week', create_at)::date AS period,
-    unnest(ST_ClusterWithin(geom3857, params.eps_week)) AS cluster_geom_3857
-  FROM geos, params
-  GROUP BY date_trunc('week', create_at)
-),
+// QueryStoredTrips 读取预先计算的行程，时间与范围条件作用于簇内的照片
+func QueryStoredTrips(p TripParams) ([]TripCluster, error) {
+	if p.radii == nil {
+		if err := p.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	levels := make([]string, 0, len(p.radii))
+	for name := range p.radii {
+		levels = append(levels, name)
+	}
+	where, args := p.tripFilter("g")
+	args = append([]interface{}{levels}, args...)
+	args = append(args, p.MinPhotos)
+
+	var result []TripCluster
+	err := db.Instance().Raw(`
+SELECT
+  c.id,
+  c.level,
+  c.period,
+  c.center_lon,
+  c.center_lat,
+  c.country,
+  c.region,
+  c.city,
+  COUNT(g.id) AS photo_count,
+  MIN(g.create_at) AS start_ts,
+  MAX(g.create_at) AS end_ts,
+  array_agg(g.id ORDER BY g.create_at) AS photo_ids
+FROM trip_clusters c
+JOIN trip_cluster_photos cp ON cp.cluster_id = c.id
+JOIN geos g ON g.id = cp.file_id
+WHERE c.level IN ?
+  AND `+where+`
+GROUP BY c.id
+HAVING COUNT(g.id) >= ?
+ORDER BY start_ts, c.level
+`, args...).Scan(&result).Error
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// QueryTrips 在满足时间与范围条件的位置中，按层级分时间段做空间聚类
+func QueryTrips(p TripParams) ([]TripCluster, error) {
+	if p.radii == nil {
+		if err := p.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	where, args := p.tripFilter("geos")
+	// 与预计算结果一致，排除地点内的照片不参与聚类
+	where += " AND " + trip.OutsideExcludedPlaces("geos")
+
+	// 层级名称来自固定表，可以直接拼入
+	var clusters []string
+	for _, level := range trip.Levels {
+		radius, ok := p.radii[level.Name]
+		if !ok {
+			continue
+		}
+		clusters = append(clusters, fmt.Sprintf(`
+  SELECT
+    '%[1]s' AS level,
+    date_trunc('%[1]s', create_at)::date AS period,
+    unnest(ST_ClusterWithin(geom3857, ?)) AS cluster_geom_3857
+  FROM filtered
+  GROUP BY date_trunc('%[1]s', create_at)`, level.Name))
+		args = append(args, radius)
+	}
+	args = append(args, p.MinPhotos)
 
-clusters_month AS (
-  SELECT 
-    date_trunc('month', create_at)::date AS period,
-    unnest(ST_ClusterWithin(geom3857, params.eps_month)) AS cluster_geom_3857
-  FROM geos, params
-  GROUP BY date_trunc('month', create_at)
+	sql := `
+WITH filtered AS MATERIALIZED (
+  SELECT id, create_at, geom3857
+  FROM geos
+  WHERE ` + where + `
 ),
 
-all_clusters AS (
-  SELECT 'day' AS level, period, cluster_geom_3857 FROM clusters_day
-  UNION ALL
-  SELECT 'week' AS level, period, cluster_geom_3857 FROM clusters_week
-  UNION ALL
-  SELECT 'month' AS level, period, cluster_geom_3857 FROM clusters_month
-)
+all_clusters AS (` + strings.Join(clusters, "\n  UNION ALL") + `
+),
 
+trips AS (
 SELECT
   level,
   period,
@@ -79,16 +312,23 @@ SELECT
   COUNT(g.id) AS photo_count,
   MIN(g.create_at) AS start_ts,
   MAX(g.create_at) AS end_ts,
-  COALESCE(array_agg(g.id), '{}') AS photo_ids
+  COALESCE(array_agg(g.id ORDER BY g.create_at), '{}') AS photo_ids
 FROM all_clusters c
-JOIN geos g 
-  ON ST_Intersects(g.geom3857, c.cluster_geom_3857)
+JOIN filtered g
+  ON date_trunc(c.level, g.create_at)::date = c.period
+ AND ST_Intersects(g.geom3857, c.cluster_geom_3857)
 GROUP BY level, period, c.cluster_geom_3857
-HAVING COUNT(g.id) >= 5;
+HAVING COUNT(g.id) >= ?
+)
+
+SELECT t.*, p.country, p.region, p.city
+FROM trips t
+LEFT JOIN LATERAL (` + trip.PlaceSQL("t.photo_ids") + `) p ON TRUE
+ORDER BY t.start_ts, t.level;
 `
-	var clusters []TripCluster
-	if err := db.Instance().Raw(sql).Scan(&clusters).Error; err != nil {
+	var result []TripCluster
+	if err := db.Instance().Raw(sql, args...).Scan(&result).Error; err != nil {
 		return nil, err
 	}
-	return clusters, nil
+	return result, nil
 }
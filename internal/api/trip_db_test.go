@@ -0,0 +1,110 @@
+package api
+
+import (
+	"ThinkBank-backend/internal/db"
+	"ThinkBank-backend/internal/db/migrate"
+	"os"
+	"slices"
+	"testing"
+	"time"
+)
+
+// openTestDB 连接 THINKBANK_TEST_DSN 指定的数据库（需安装 postgis 与 vector）并执行迁移，未设置时跳过
+func openTestDB(t *testing.T) {
+	t.Helper()
+	dsn := os.Getenv("THINKBANK_TEST_DSN")
+	if dsn == "" {
+		t.Skip("THINKBANK_TEST_DSN not set")
+	}
+	if err := db.Open(dsn); err != nil {
+		t.Fatal(err)
+	}
+	if err := migrate.Up(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// seedGeos 写入测试位置，测试结束后删除
+func seedGeos(t *testing.T, day time.Time, points map[int64][2]float64) {
+	t.Helper()
+	ids := make([]int64, 0, len(points))
+	i := 0
+	for id, p := range points {
+		ts := day.Add(time.Duration(i) * time.Minute)
+		err := db.Instance().Exec("INSERT INTO geos (id, latitude, longitude, create_at) VALUES (?, ?, ?, ?)",
+			id, p[0], p[1], ts).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+		i++
+	}
+	t.Cleanup(func() {
+		db.Instance().Exec("DELETE FROM geos WHERE id IN ?", ids)
+		db.Instance().Exec("DELETE FROM trip_dirty_periods WHERE period < ?", day.AddDate(1, 0, 0))
+	})
+}
+
+func TestQueryTrips(t *testing.T) {
+	openTestDB(t)
+
+	day := time.Date(2001, 6, 10, 12, 0, 0, 0, time.UTC)
+	paris := []int64{990000101, 990000102, 990000103}
+	rome := []int64{990000201, 990000202}
+	berlin := int64(990000301)
+	seedGeos(t, day, map[int64][2]float64{
+		paris[0]: {48.8566, 2.3522},
+		paris[1]: {48.8606, 2.3376},
+		paris[2]: {48.8530, 2.3499},
+		rome[0]:  {41.9028, 12.4964},
+		rome[1]:  {41.8902, 12.4922},
+		berlin:   {52.5200, 13.4050},
+	})
+
+	var unset []int64
+	err := db.Instance().Raw("SELECT id FROM geos WHERE id IN ? AND geom3857 IS NULL",
+		append(append(append([]int64{}, paris...), rome...), berlin)).Scan(&unset).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unset) > 0 {
+		t.Fatalf("geom3857 not populated by trigger for %v", unset)
+	}
+
+	from, to := day.AddDate(0, 0, -1), day.AddDate(0, 0, 1)
+	cases := []struct {
+		name   string
+		radius float64
+		want   [][]int64 // 按照片数从多到少排列的各行程照片，成员不分先后
+	}{
+		{"default radius", 0, [][]int64{paris, rome}},
+		{"merge all", 3000000, [][]int64{append(append(append([]int64{}, paris...), rome...), berlin)}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			trips, err := QueryTrips(TripParams{
+				Levels:    []string{"day"},
+				Radius:    c.radius,
+				MinPhotos: 2,
+				From:      &from,
+				To:        &to,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(trips) != len(c.want) {
+				t.Fatalf("got %d trips, want %d: %+v", len(trips), len(c.want), trips)
+			}
+			slices.SortFunc(trips, func(a, b TripCluster) int { return b.PhotoCount - a.PhotoCount })
+			for i, want := range c.want {
+				got := slices.Clone([]int64(trips[i].PhotoIDs))
+				slices.Sort(got)
+				want = slices.Clone(want)
+				slices.Sort(want)
+				if !slices.Equal(got, want) || trips[i].PhotoCount != len(want) {
+					t.Errorf("trip %d: photos %v (count %d), want %v", i, got, trips[i].PhotoCount, want)
+				}
+			}
+		})
+	}
+}
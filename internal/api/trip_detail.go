@@ -0,0 +1,262 @@
+package api
+
+import (
+	"ThinkBank-backend/internal/db"
+	"ThinkBank-backend/internal/model"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+const maxTripHighlights = 5
+
+var errTripNotFound = errors.New("trip not found")
+
+// TripPhoto 行程中的一张照片
+type TripPhoto struct {
+	ID           uint      `json:"id"`
+	FileName     string    `json:"fileName"`
+	ThumbnailURL string    `json:"thumbnailUrl"`
+	Caption      string    `json:"caption"`
+	TakenAt      time.Time `json:"takenAt"`
+	Latitude     float64   `json:"latitude"`
+	Longitude    float64   `json:"longitude"`
+	Country      *string   `json:"country"`
+	Region       *string   `json:"region"`
+	City         *string   `json:"city"`
+	Day          string    `json:"day"`
+}
+
+// TripDay 行程中的一天
+type TripDay struct {
+	Date           string   `json:"date"`
+	PhotoCount     int      `json:"photoCount"`
+	Places         []string `json:"places"` // 按首次出现的顺序
+	DistanceMeters float64  `json:"distanceMeters"`
+}
+
+// TripDetail 行程详情
+type TripDetail struct {
+	TripCluster
+	Photos         []TripPhoto     `json:"photos"`
+	Itinerary      []TripDay       `json:"itinerary"`
+	Route          json.RawMessage `json:"route"` // GeoJSON LineString，按拍摄时间连接各照片位置
+	DistanceMeters float64         `json:"distanceMeters"`
+	Summary        string          `json:"summary"`
+	Highlights     []string        `json:"highlights"` // 从模型生成的描述中挑选
+}
+
+// QueryTripDetail 读取预计算行程的照片、逐日行程、路线与摘要
+func QueryTripDetail(id uint) (*TripDetail, error) {
+	var clusters []TripCluster
+	err := db.Instance().Raw(`
+        SELECT id, level, period, center_lon, center_lat, photo_count, start_ts, end_ts,
+               country, region, city
+        FROM trip_clusters
+        WHERE id = ?
+    `, id).Scan(&clusters).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(clusters) == 0 {
+		return nil, errTripNotFound
+	}
+	detail := &TripDetail{TripCluster: clusters[0]}
+
+	// 日期按数据库会话时区划分，与行程的时间段一致
+	var rows []struct {
+		ID               uint
+		FileName         string
+		Type             string
+		FilePath         string
+		OriginalFilePath string
+		Caption          string
+		TakenAt          time.Time
+		Latitude         float64
+		Longitude        float64
+		Country          *string
+		Region           *string
+		City             *string
+		Day              string
+	}
+	err = db.Instance().Raw(`
+        SELECT f.id, f.file_name, f.type, f.file_path, f.original_file_path, f.caption,
+               g.create_at AS taken_at, g.latitude, g.longitude, g.country, g.region, g.city,
+               date_trunc('day', g.create_at)::date::text AS day
+        FROM trip_cluster_photos cp
+        JOIN geos g ON g.id = cp.file_id
+        JOIN files f ON f.id = g.id AND f.deleted_at IS NULL
+        WHERE cp.cluster_id = ?
+        ORDER BY g.create_at, g.id
+    `, id).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	detail.Photos = make([]TripPhoto, len(rows))
+	detail.PhotoIDs = make([]int64, len(rows))
+	for i, r := range rows {
+		f := model.File{Type: r.Type, FilePath: r.FilePath, OriginalFilePath: r.OriginalFilePath}
+		detail.Photos[i] = TripPhoto{
+			ID:           r.ID,
+			FileName:     r.FileName,
+			ThumbnailURL: f.ThumbnailURL(),
+			Caption:      r.Caption,
+			TakenAt:      r.TakenAt,
+			Latitude:     r.Latitude,
+			Longitude:    r.Longitude,
+			Country:      r.Country,
+			Region:       r.Region,
+			City:         r.City,
+			Day:          r.Day,
+		}
+		detail.PhotoIDs[i] = int64(r.ID)
+	}
+	detail.PhotoCount = len(rows)
+
+	var route struct {
+		Route    *string // 没有照片时为空
+		Distance float64
+	}
+	err = db.Instance().Raw(`
+        WITH line AS (
+            SELECT ST_MakeLine(g.geom ORDER BY g.create_at, g.id) AS geom
+            FROM trip_cluster_photos cp
+            JOIN geos g ON g.id = cp.file_id
+            JOIN files f ON f.id = g.id AND f.deleted_at IS NULL
+            WHERE cp.cluster_id = ?
+        )
+        SELECT ST_AsGeoJSON(geom) AS route, COALESCE(ST_Length(geom::geography), 0) AS distance
+        FROM line
+    `, id).Scan(&route).Error
+	if err != nil {
+		return nil, err
+	}
+	if route.Route != nil {
+		detail.Route = json.RawMessage(*route.Route)
+	}
+	detail.DistanceMeters = route.Distance
+
+	var days []struct {
+		Day      string
+		Distance float64
+	}
+	err = db.Instance().Raw(`
+        SELECT date_trunc('day', g.create_at)::date::text AS day,
+               COALESCE(ST_Length(ST_MakeLine(g.geom ORDER BY g.create_at, g.id)::geography), 0) AS distance
+        FROM trip_cluster_photos cp
+        JOIN geos g ON g.id = cp.file_id
+        JOIN files f ON f.id = g.id AND f.deleted_at IS NULL
+        WHERE cp.cluster_id = ?
+        GROUP BY 1
+    `, id).Scan(&days).Error
+	if err != nil {
+		return nil, err
+	}
+	dayDistances := make(map[string]float64, len(days))
+	for _, d := range days {
+		dayDistances[d.Day] = d.Distance
+	}
+
+	detail.Itinerary = buildItinerary(detail.Photos, dayDistances)
+	detail.Summary, detail.Highlights = tripSummary(detail)
+	return detail, nil
+}
+
+// placeLabel 城市与国家，城市缺失时使用地区
+func placeLabel(country, region, city *string) string {
+	var parts []string
+	switch {
+	case city != nil && *city != "":
+		parts = append(parts, *city)
+	case region != nil && *region != "":
+		parts = append(parts, *region)
+	}
+	if country != nil && *country != "" {
+		parts = append(parts, *country)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// buildItinerary 按天汇总照片数与去过的地方，照片已按时间排序
+func buildItinerary(photos []TripPhoto, distances map[string]float64) []TripDay {
+	days := []TripDay{}
+	for _, p := range photos {
+		if len(days) == 0 || days[len(days)-1].Date != p.Day {
+			days = append(days, TripDay{Date: p.Day, Places: []string{}, DistanceMeters: distances[p.Day]})
+		}
+		day := &days[len(days)-1]
+		day.PhotoCount++
+		if label := placeLabel(p.Country, p.Region, p.City); label != "" && !slices.Contains(day.Places, label) {
+			day.Places = append(day.Places, label)
+		}
+	}
+	return days
+}
+
+// tripSummary 由地点、日期、距离与照片描述生成文字摘要，描述去重后在整个行程中均匀挑选
+func tripSummary(d *TripDetail) (string, []string) {
+	var captions []string
+	seen := make(map[string]bool)
+	for _, p := range d.Photos {
+		caption := strings.TrimSpace(p.Caption)
+		key := strings.ToLower(caption)
+		if caption == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		captions = append(captions, caption)
+	}
+	highlights := []string{}
+	if n := len(captions); n <= maxTripHighlights {
+		highlights = append(highlights, captions...)
+	} else {
+		for i := 0; i < maxTripHighlights; i++ {
+			highlights = append(highlights, captions[i*(n-1)/(maxTripHighlights-1)])
+		}
+	}
+
+	var b strings.Builder
+	if len(d.Itinerary) > 1 {
+		fmt.Fprintf(&b, "%d-day trip", len(d.Itinerary))
+	} else {
+		b.WriteString("Day trip")
+	}
+	if place := placeLabel(d.Country, d.Region, d.City); place != "" {
+		fmt.Fprintf(&b, " to %s", place)
+	}
+	if len(d.Photos) > 0 {
+		start, end := d.Photos[0].TakenAt.In(time.Local), d.Photos[len(d.Photos)-1].TakenAt.In(time.Local)
+		if start.Format("2006-01-02") == end.Format("2006-01-02") {
+			fmt.Fprintf(&b, " on %s", start.Format("Jan 2, 2006"))
+		} else if start.Year() == end.Year() {
+			fmt.Fprintf(&b, ", %s – %s", start.Format("Jan 2"), end.Format("Jan 2, 2006"))
+		} else {
+			fmt.Fprintf(&b, ", %s – %s", start.Format("Jan 2, 2006"), end.Format("Jan 2, 2006"))
+		}
+	}
+	fmt.Fprintf(&b, ": %d photos", len(d.Photos))
+	if d.DistanceMeters >= 1000 {
+		fmt.Fprintf(&b, " over %.1f km", d.DistanceMeters/1000)
+	}
+	b.WriteString(".")
+
+	var places []string
+	for _, day := range d.Itinerary {
+		for _, p := range day.Places {
+			if !slices.Contains(places, p) {
+				places = append(places, p)
+			}
+		}
+	}
+	if len(places) > 1 {
+		fmt.Fprintf(&b, " Visited %s.", strings.Join(places, "; "))
+	}
+	if len(highlights) > 0 {
+		fmt.Fprintf(&b, " Highlights: %s.", strings.Join(highlights, "; "))
+	}
+	return b.String(), highlights
+}
@@ -0,0 +1,168 @@
+package api
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+func str(s string) *string { return &s }
+
+func TestPlaceLabel(t *testing.T) {
+	cases := []struct {
+		country, region, city *string
+		want                  string
+	}{
+		{str("Japan"), str("Kyoto"), str("Kyoto City"), "Kyoto City, Japan"},
+		{str("Japan"), str("Kyoto"), nil, "Kyoto, Japan"},
+		{str("Japan"), str("Kyoto"), str(""), "Kyoto, Japan"},
+		{str("Japan"), nil, nil, "Japan"},
+		{nil, nil, str("Kyoto City"), "Kyoto City"},
+		{nil, nil, nil, ""},
+	}
+	for _, c := range cases {
+		if got := placeLabel(c.country, c.region, c.city); got != c.want {
+			t.Errorf("placeLabel = %q, want %q", got, c.want)
+		}
+	}
+}
+
+func TestBuildItinerary(t *testing.T) {
+	photo := func(day string, city *string) TripPhoto {
+		return TripPhoto{Day: day, Country: str("Japan"), City: city}
+	}
+	cases := []struct {
+		name      string
+		photos    []TripPhoto
+		distances map[string]float64
+		want      []TripDay
+	}{
+		{
+			name: "no photos",
+			want: []TripDay{},
+		},
+		{
+			name: "places in order of first visit",
+			photos: []TripPhoto{
+				photo("2023-04-01", str("Kyoto")),
+				photo("2023-04-01", str("Nara")),
+				photo("2023-04-01", str("Kyoto")),
+				photo("2023-04-02", str("Osaka")),
+			},
+			distances: map[string]float64{"2023-04-01": 42000},
+			want: []TripDay{
+				{Date: "2023-04-01", PhotoCount: 3, Places: []string{"Kyoto, Japan", "Nara, Japan"}, DistanceMeters: 42000},
+				{Date: "2023-04-02", PhotoCount: 1, Places: []string{"Osaka, Japan"}},
+			},
+		},
+		{
+			name:   "photos without place",
+			photos: []TripPhoto{{Day: "2023-04-01"}, {Day: "2023-04-01"}},
+			want:   []TripDay{{Date: "2023-04-01", PhotoCount: 2, Places: []string{}}},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := buildItinerary(c.photos, c.distances); !reflect.DeepEqual(got, c.want) {
+				t.Errorf("buildItinerary = %+v, want %+v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestTripSummary(t *testing.T) {
+	at := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 12, 0, 0, 0, time.Local)
+	}
+	photos := func(captions []string, times ...time.Time) []TripPhoto {
+		ps := make([]TripPhoto, len(times))
+		for i, ts := range times {
+			ps[i] = TripPhoto{TakenAt: ts}
+			if i < len(captions) {
+				ps[i].Caption = captions[i]
+			}
+		}
+		return ps
+	}
+	days := func(places ...[]string) []TripDay {
+		ds := make([]TripDay, len(places))
+		for i, p := range places {
+			ds[i] = TripDay{Places: p}
+		}
+		return ds
+	}
+
+	var many []string
+	var manyTimes []time.Time
+	for i := 0; i < 7; i++ {
+		many = append(many, fmt.Sprintf("caption %d", i))
+		manyTimes = append(manyTimes, at(2023, 4, 1))
+	}
+
+	cases := []struct {
+		name           string
+		detail         TripDetail
+		wantSummary    string
+		wantHighlights []string
+	}{
+		{
+			name: "day trip",
+			detail: TripDetail{
+				TripCluster: TripCluster{Country: str("Japan"), City: str("Kyoto")},
+				Photos:      photos(nil, at(2023, 4, 1), at(2023, 4, 1)),
+				Itinerary:   days([]string{"Kyoto, Japan"}),
+			},
+			wantSummary:    "Day trip to Kyoto, Japan on Apr 1, 2023: 2 photos.",
+			wantHighlights: []string{},
+		},
+		{
+			name: "multi-day trip with places, distance and highlights",
+			detail: TripDetail{
+				TripCluster:    TripCluster{Country: str("Japan")},
+				Photos:         photos([]string{"Temple", " temple ", "", "Deer park"}, at(2023, 4, 1), at(2023, 4, 1), at(2023, 4, 2), at(2023, 4, 3)),
+				Itinerary:      days([]string{"Kyoto, Japan"}, []string{"Nara, Japan", "Kyoto, Japan"}, nil),
+				DistanceMeters: 48300,
+			},
+			wantSummary:    "3-day trip to Japan, Apr 1 – Apr 3, 2023: 4 photos over 48.3 km. Visited Kyoto, Japan; Nara, Japan. Highlights: Temple; Deer park.",
+			wantHighlights: []string{"Temple", "Deer park"},
+		},
+		{
+			name: "across years, short distance omitted",
+			detail: TripDetail{
+				Photos:         photos(nil, at(2022, 12, 31), at(2023, 1, 1)),
+				Itinerary:      days(nil, nil),
+				DistanceMeters: 999,
+			},
+			wantSummary:    "2-day trip, Dec 31, 2022 – Jan 1, 2023: 2 photos.",
+			wantHighlights: []string{},
+		},
+		{
+			name:           "no photos",
+			detail:         TripDetail{},
+			wantSummary:    "Day trip: 0 photos.",
+			wantHighlights: []string{},
+		},
+		{
+			name: "highlights evenly spaced",
+			detail: TripDetail{
+				Photos:    photos(many, manyTimes...),
+				Itinerary: days(nil),
+			},
+			wantSummary:    "Day trip on Apr 1, 2023: 7 photos. Highlights: caption 0; caption 1; caption 3; caption 4; caption 6.",
+			wantHighlights: []string{"caption 0", "caption 1", "caption 3", "caption 4", "caption 6"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			summary, highlights := tripSummary(&c.detail)
+			if summary != c.wantSummary {
+				t.Errorf("summary = %q, want %q", summary, c.wantSummary)
+			}
+			if !slices.Equal(highlights, c.wantHighlights) {
+				t.Errorf("highlights = %q, want %q", highlights, c.wantHighlights)
+			}
+		})
+	}
+}
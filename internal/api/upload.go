@@ -1,14 +1,12 @@
 package api
 
 import (
-	"ThinkBank-backend/internal/queue"
+	"ThinkBank-backend/internal/ingest"
 	"ThinkBank-backend/internal/service"
-	"ThinkBank-backend/internal/util"
 	"fmt"
+	"io"
 	"mime/multipart"
-	"time"
 
-	"ThinkBank-backend/internal/db"
 	"ThinkBank-backend/internal/model"
 
 	"github.com/gofiber/fiber/v2"
@@ -35,7 +33,7 @@ func UploadHandler(fileService service.FileService) fiber.Handler {
 		results := make([]map[string]interface{}, 0, len(files))
 
 		for _, file := range files {
-			record, err := processSingleFile(file, fileService)
+			record, duplicate, err := processSingleFile(file, fileService)
 			if err != nil {
 				return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 			}
@@ -44,6 +42,7 @@ func UploadHandler(fileService service.FileService) fiber.Handler {
 				"fileName":         record.FileName,
 				"originalFilePath": record.OriginalFilePath,
 				"type":             record.Type,
+				"duplicate":        duplicate,
 			})
 		}
 
@@ -56,11 +55,11 @@ func UploadHandler(fileService service.FileService) fiber.Handler {
 }
 
 // processSingleFile 处理单个文件上传逻辑
-func processSingleFile(file *multipart.FileHeader, fileService service.FileService) (*model.File, error) {
+func processSingleFile(file *multipart.FileHeader, fileService service.FileService) (*model.File, bool, error) {
 	// 打开文件
 	f, err := file.Open()
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 	defer func() {
 		if cerr := f.Close(); cerr != nil {
@@ -69,37 +68,9 @@ func processSingleFile(file *multipart.FileHeader, fileService service.FileServi
 	}()
 
 	data := make([]byte, file.Size)
-	if _, err := f.Read(data); err != nil {
-		return nil, err
+	if _, err := io.ReadFull(f, data); err != nil {
+		return nil, false, err
 	}
 
-	// 先在数据库创建记录
-	fileRecord := &model.File{
-		FileName: file.Filename,
-		Type:     util.GetFileType(file.Filename),
-	}
-	if err := db.Instance().Create(fileRecord).Error; err != nil {
-		return nil, err
-	}
-
-	// 构造存储路径
-	subPath := time.Now().Format("2006/01/02")
-	storedFileName := fmt.Sprintf("%d%s", fileRecord.ID, util.GetFileExt(file.Filename))
-
-	// 保存文件
-	savedPath, err := fileService.Put(storedFileName, data, subPath)
-	if err != nil {
-		db.Instance().Delete(fileRecord)
-		return nil, err
-	}
-
-	// 更新数据库路径
-	fileRecord.OriginalFilePath = savedPath
-	if err := db.Instance().Where("id = ?", fileRecord.ID).Updates(fileRecord).Error; err != nil {
-		return nil, err
-	}
-
-	queue.ProduceNormalizeFile(fileRecord.ID, savedPath)
-
-	return fileRecord, nil
+	return ingest.File(file.Filename, data, fileService, nil)
 }
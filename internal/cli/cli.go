@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"ThinkBank-backend/internal/db"
+	"ThinkBank-backend/internal/service"
+	"ThinkBank-backend/internal/vector"
+	"fmt"
+	"os"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const usage = `Usage: thinkbank <command> [arguments]
+
+Commands:
+  serve              start the HTTP API (default)
+  migrate up|down [steps]|status
+                     manage the database schema
+  worker             run queue consumers and periodic tasks without HTTP
+  import <dir>       import files from a local directory
+  reindex            re-run normalization / embedding for existing files
+  gc                 purge deleted files and temporary uploads
+  recall             measure HNSW recall against exact search
+  geonames <cities>  load GeoNames cities for offline reverse geocoding
+
+Run "thinkbank <command> -h" for command flags.
+`
+
+// Run 解析子命令并执行，未指定子命令时启动 HTTP 服务
+func Run(args []string) error {
+	if len(args) == 0 {
+		return Serve(nil)
+	}
+
+	switch args[0] {
+	case "serve":
+		return Serve(args[1:])
+	case "migrate":
+		return Migrate(args[1:])
+	case "worker":
+		return Worker(args[1:])
+	case "import":
+		return Import(args[1:])
+	case "reindex":
+		return Reindex(args[1:])
+	case "gc":
+		return GC(args[1:])
+	case "recall":
+		return Recall(args[1:])
+	case "geonames":
+		return GeoNames(args[1:])
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return nil
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("unknown command: %s", args[0])
+	}
+}
+
+// setupDatabase 连接 PostgreSQL 并加载向量相关配置
+func setupDatabase() {
+	db.InitPostgres(
+		os.Getenv("POSTGRE_USER"),
+		os.Getenv("POSTGRE_PASSWORD"),
+		os.Getenv("POSTGRE_DB"),
+		os.Getenv("POSTGRE_HOST"),
+		os.Getenv("POSTGRE_PORT"),
+	)
+
+	// 向量度量，需在建立索引前配置
+	vector.Configure(os.Getenv("VECTOR_METRIC"), os.Getenv("VECTOR_NORMALIZE") == "1")
+	vector.ConfigureHNSW(os.Getenv("HNSW_M"), os.Getenv("HNSW_EF_CONSTRUCTION"), os.Getenv("HNSW_EF_SEARCH"))
+	vector.ConfigureModels(os.Getenv("EMBEDDING_MODEL"), os.Getenv("REEMBED_MODEL"))
+}
+
+// fileServices 各类存储
+type fileServices struct {
+	original   *service.LocalFileService
+	normalized *service.LocalFileService
+	tmp        *service.LocalFileService
+}
+
+// newFileServices 创建存储，app 为 nil 时不注册静态路由
+func newFileServices(app fiber.Router) fileServices {
+	backendURL := os.Getenv("BACKEND_URL")
+	return fileServices{
+		original: service.NewLocalFileService(
+			app, backendURL, "/uploads/original", "./uploads/original"),
+		normalized: service.NewLocalFileService(
+			app, backendURL, "/uploads/normalized", "./uploads/normalized"),
+		tmp: service.NewLocalFileService(
+			app, backendURL, "/uploads/tmp", "./uploads/tmp"),
+	}
+}
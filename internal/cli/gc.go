@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"ThinkBank-backend/internal/db"
+	"ThinkBank-backend/internal/model"
+	"ThinkBank-backend/internal/service"
+	"flag"
+	"log"
+	"time"
+)
+
+// GC 清理临时上传、已软删除的文件及其存储，以及失去关联的向量与地理记录
+func GC(args []string) error {
+	fs := flag.NewFlagSet("gc", flag.ExitOnError)
+	olderThan := fs.Duration("older-than", 30*24*time.Hour, "purge files soft-deleted longer than this")
+	tmpOlderThan := fs.Duration("tmp-older-than", 180*time.Second, "remove temporary uploads older than this")
+	dryRun := fs.Bool("dry-run", false, "only report what would be removed")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	setupDatabase()
+	files := newFileServices(nil)
+
+	// 1. 临时文件
+	if !*dryRun {
+		if err := service.ClearFiles(files.tmp, "", *tmpOlderThan); err != nil {
+			return err
+		}
+	}
+
+	// 2. 软删除超过期限的文件
+	var deleted []model.File
+	err := db.Instance().Unscoped().
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", time.Now().Add(-*olderThan)).
+		Find(&deleted).Error
+	if err != nil {
+		return err
+	}
+	log.Printf("%d soft-deleted files to purge", len(deleted))
+
+	if !*dryRun {
+		for _, f := range deleted {
+			removeStored(files.original, f.OriginalFilePath)
+			removeStored(files.normalized, f.FilePath)
+			if err := db.Instance().Unscoped().Delete(&model.File{}, f.ID).Error; err != nil {
+				log.Printf("Failed to purge file %d: %v", f.ID, err)
+			}
+		}
+	}
+
+	// 3. 失去关联的向量与地理记录
+	for _, table := range []string{"embeddings", "reembedding_failures", "geos"} {
+		column := "file_id"
+		if table == "geos" {
+			column = "id"
+		}
+		sql := "FROM " + table + " t WHERE NOT EXISTS (SELECT 1 FROM files f WHERE f.id = t." + column + ")"
+
+		var count int64
+		if *dryRun {
+			err = db.Instance().Raw("SELECT COUNT(*) " + sql).Scan(&count).Error
+		} else {
+			result := db.Instance().Exec("DELETE " + sql)
+			count, err = result.RowsAffected, result.Error
+		}
+		if err != nil {
+			return err
+		}
+		log.Printf("%d orphaned %s rows", count, table)
+	}
+
+	return nil
+}
+
+func removeStored(fs *service.LocalFileService, url string) {
+	if url == "" {
+		return
+	}
+	subPath, ok := fs.SubPath(url)
+	if !ok {
+		return
+	}
+	if err := fs.Delete(subPath); err != nil {
+		log.Printf("Failed to delete %s: %v", url, err)
+	}
+}
@@ -0,0 +1,37 @@
+package cli
+
+import (
+	"ThinkBank-backend/internal/geocode"
+	"errors"
+	"flag"
+	"log"
+)
+
+// GeoNames 导入 GeoNames 城市数据用于离线逆地理编码，并重新解析已有位置的地名
+func GeoNames(args []string) error {
+	flags := flag.NewFlagSet("geonames", flag.ExitOnError)
+	admin1 := flags.String("admin1", "", "admin1CodesASCII.txt for region names")
+	countries := flags.String("countries", "", "countryInfo.txt for country names")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: geonames [flags] <cities.txt>")
+	}
+
+	setupDatabase()
+
+	n, err := geocode.Load(geocode.Sources{Cities: flags.Arg(0), Admin1: *admin1, Countries: *countries})
+	if err != nil {
+		return err
+	}
+	log.Printf("Loaded %d places", n)
+
+	updated, err := geocode.Backfill()
+	if err != nil {
+		return err
+	}
+	// 行程地名由 serve / worker 的后台刷新更新
+	log.Printf("Updated place names for %d locations", updated)
+	return nil
+}
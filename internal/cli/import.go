@@ -0,0 +1,31 @@
+package cli
+
+import (
+	"ThinkBank-backend/internal/ingest"
+	"errors"
+	"flag"
+	"log"
+)
+
+// Import 把本地目录（包括带 JSON sidecar 的 Google Takeout 导出）写入存储并创建记录，
+// 中断后重新执行会跳过已导入的文件。命令行进程内没有消费者，
+// 文件会由 serve / worker 的 ScheduleStalledFiles 接手处理
+func Import(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "only list files that would be imported")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: import [flags] <dir>")
+	}
+
+	setupDatabase()
+	files := newFileServices(nil)
+
+	importer := &ingest.Importer{FileService: files.original, DryRun: *dryRun}
+	stats, err := importer.ImportDir(flags.Arg(0))
+	log.Printf("Import finished: %d imported, %d duplicates, %d skipped, %d failed",
+		stats.Imported, stats.Duplicates, stats.Skipped, stats.Failed)
+	return err
+}
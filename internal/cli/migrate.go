@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"ThinkBank-backend/internal/db/migrate"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+)
+
+// Migrate 管理数据库结构：up / down / status
+func Migrate(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: migrate up|down [steps]|status")
+	}
+
+	setupDatabase()
+
+	switch args[0] {
+	case "up":
+		migrateUp()
+		return nil
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n <= 0 {
+				return fmt.Errorf("invalid steps: %s", args[1])
+			}
+			steps = n
+		}
+		return migrate.Down(steps)
+	case "status":
+		statuses, err := migrate.CheckStatus()
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			mark := "pending"
+			if s.Applied {
+				mark = "applied"
+			}
+			appliedAt := ""
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%-8s %-30s %s\n", mark, s.Name, appliedAt)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command: %s", args[0])
+	}
+}
+
+// migrateUp 执行未应用的版本化迁移，再同步向量索引
+func migrateUp() {
+	if err := migrate.Up(); err != nil {
+		log.Fatal("Database migration failed:", err)
+	}
+	migrate.SyncVectorIndices()
+}
@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"ThinkBank-backend/internal/api/search"
+	"flag"
+	"fmt"
+	"log"
+)
+
+// Recall 对比 HNSW 与暴力检索的召回率，用于调整 ef_search 等检索参数
+func Recall(args []string) error {
+	fs := flag.NewFlagSet("recall", flag.ExitOnError)
+	samples := fs.Int("samples", 50, "number of stored vectors used as queries")
+	k := fs.Int("k", 10, "number of neighbours compared per query")
+	efSearch := fs.Int("ef-search", 0, "hnsw.ef_search (0 uses HNSW_EF_SEARCH)")
+	iterativeScan := fs.String("iterative-scan", "", "hnsw.iterative_scan: off / relaxed_order / strict_order (empty chooses automatically)")
+	maxScanTuples := fs.Int("max-scan-tuples", 0, "hnsw.max_scan_tuples")
+	minSimilarity := fs.Float64("min-similarity", 0, "minimum similarity within [0, 1]")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *samples <= 0 || *samples > 1000 {
+		return fmt.Errorf("samples must be within [1, 1000]")
+	}
+	if *k <= 0 {
+		return fmt.Errorf("k must be positive")
+	}
+
+	// 默认检索参数来自环境变量，需先加载配置
+	setupDatabase()
+
+	opts, err := search.VectorParams{
+		MinSimilarity: *minSimilarity,
+		EfSearch:      *efSearch,
+		IterativeScan: *iterativeScan,
+		MaxScanTuples: *maxScanTuples,
+	}.Resolve()
+	if err != nil {
+		return err
+	}
+
+	report, err := search.MeasureRecall(*samples, *k, opts)
+	if err != nil {
+		return err
+	}
+	log.Printf("recall@%d over %d samples (ef_search=%d, iterative_scan=%q): avg %.4f, min %.4f",
+		report.K, report.Samples, report.Params.EfSearch, report.Params.IterativeScan, report.Recall, report.MinRecall)
+	log.Printf("avg latency: hnsw %.2f ms, exact %.2f ms", report.AvgANNLatencyMs, report.AvgExactLatencyMs)
+	return nil
+}
@@ -0,0 +1,155 @@
+package cli
+
+import (
+	"ThinkBank-backend/internal/queue"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Reindex 请求运行中的服务端重新处理文件。队列位于服务端进程内，
+// 因此命令行通过管理接口创建任务并轮询进度，服务端需要同时运行队列消费者并配置 ADMIN_TOKEN
+func Reindex(args []string) error {
+	fs := flag.NewFlagSet("reindex", flag.ExitOnError)
+	server := fs.String("server", os.Getenv("BACKEND_URL"), "backend URL")
+	token := fs.String("token", os.Getenv("ADMIN_TOKEN"), "admin token configured on the server")
+	normalize := fs.Bool("normalize", false, "re-run normalization (followed by embedding)")
+	embedding := fs.Bool("embedding", false, "re-run embedding only")
+	fileType := fs.String("type", "", "only files of this type (image / document)")
+	from := fs.String("from", "", "uploaded on or after this date (2006-01-02)")
+	to := fs.String("to", "", "uploaded before this date (2006-01-02)")
+	missingCaption := fs.Bool("missing-caption", false, "only files without caption")
+	missingVector := fs.Bool("missing-vector", false, "only files without embedding for the active model")
+	failed := fs.Bool("failed", false, "only files whose last processing failed")
+	wait := fs.Bool("wait", true, "poll progress until all files are processed")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	req := queue.ReindexRequest{
+		ReindexFilter: queue.ReindexFilter{
+			Type:           *fileType,
+			MissingCaption: *missingCaption,
+			MissingVector:  *missingVector,
+			Failed:         *failed,
+		},
+		Normalize: *normalize,
+		Embedding: *embedding,
+	}
+	var err error
+	if req.From, err = parseDate(*from); err != nil {
+		return err
+	}
+	if req.To, err = parseDate(*to); err != nil {
+		return err
+	}
+
+	var job queue.Job
+	if err := postJSON(*server+"/admin/reindex", *token, req, &job); err != nil {
+		return err
+	}
+	log.Printf("Reindex job %s created, %d files matched", job.ID, job.Total)
+
+	if !*wait {
+		return nil
+	}
+	return pollJob(*server+"/admin/reindex/"+job.ID, *token, &job)
+}
+
+func parseDate(val string) (*time.Time, error) {
+	if val == "" {
+		return nil, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", val, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date %q: %w", val, err)
+	}
+	return &t, nil
+}
+
+// pollJob 轮询任务直到最后一个阶段处理完所有文件
+func pollJob(url, token string, job *queue.Job) error {
+	for {
+		if err := getJSON(url, token, job); err != nil {
+			return err
+		}
+
+		complete := job.Finished
+		for topic, s := range job.Stages {
+			log.Printf("  %s: %d/%d done, %d failed", topic, s.Done, s.Queued, s.Failed)
+			if s.Done+s.Failed < s.Queued {
+				complete = false
+			}
+		}
+		if last, ok := job.Stages["embedding_file"]; ok && job.Finished {
+			// 规范化失败的文件不会进入 embedding 阶段
+			normalizeFailed := int64(0)
+			if n, ok := job.Stages["normalize_file"]; ok {
+				normalizeFailed = n.Failed
+			}
+			if last.Queued+normalizeFailed < job.Total {
+				complete = false
+			}
+		}
+		if job.Error != "" {
+			return fmt.Errorf("reindex job failed: %s", job.Error)
+		}
+		if complete {
+			log.Printf("Reindex job %s completed", job.ID)
+			return nil
+		}
+		time.Sleep(2 * time.Second)
+	}
+}
+
+func postJSON(url, token string, body, out any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return doJSON(req, token, out)
+}
+
+func getJSON(url, token string, out any) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	return doJSON(req, token, out)
+}
+
+func doJSON(req *http.Request, token string, out any) error {
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	return decodeResponse(resp, out)
+}
+
+func decodeResponse(resp *http.Response, out any) error {
+	defer func() {
+		if e := resp.Body.Close(); e != nil {
+			log.Println("Failed to close response body:", e)
+		}
+	}()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("server error: %s %s", resp.Status, bytes.TrimSpace(body))
+	}
+	return json.Unmarshal(body, out)
+}
@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"ThinkBank-backend/internal/api"
+	"ThinkBank-backend/internal/api/search"
+	"ThinkBank-backend/internal/service"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/cors"
+)
+
+// Serve 启动 HTTP 服务
+func Serve(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	runMigrate := fs.Bool("migrate", true, "apply database migrations before serving")
+	runWorkers := fs.Bool("workers", true, "run queue consumers in this process (disable when a separate worker is deployed)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	setupDatabase()
+
+	// 数据库的迁移
+	if *runMigrate {
+		migrateUp()
+	}
+
+	// fiber 实例
+	app := fiber.New(fiber.Config{
+		BodyLimit: 1000 * 1024 * 1024, // 100 MB
+	})
+
+	// CORS 中间件
+	app.Use(cors.New(cors.Config{
+		AllowOrigins: os.Getenv("FRONTEND_URL"),
+		AllowMethods: "*",
+		AllowHeaders: "*",
+	}))
+
+	// 文件服务
+	files := newFileServices(app)
+
+	service.RegisterFileCleaner(files.tmp, "", 180*time.Second, 180*time.Second)
+
+	// 模型服务
+	modelService := service.NewHTTPModelService(os.Getenv("MODEL_SERVICE_URL"))
+
+	// 路由
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString("Hello, ThinkBank!")
+	})
+
+	api.RegisterUploadRoutes(app, files.original)
+	api.RegisterFileListRoute(app)
+	api.RegisterLocationRoutes(app)
+	api.RegisterPlaceRoutes(app)
+	api.RegisterTripRoutes(app)
+	api.RegisterMapRoutes(app)
+	api.RegisterTileRoutes(app)
+	api.RegisterTimelineRoutes(app)
+	api.RegisterEmbeddingRoutes(app)
+	api.RegisterReindexRoutes(app, *runWorkers, os.Getenv("ADMIN_TOKEN"))
+	api.RegisterExportRoutes(app, modelService, files.original, files.normalized)
+	search.RegisterSearchByText(app, modelService)
+	search.RegisterSearchByImage(app, modelService, files.tmp)
+	search.RegisterSearchSimilar(app)
+
+	// 消息队列
+	if *runWorkers {
+		startWorkers(files, modelService)
+	}
+
+	// 端口监听
+	return app.Listen(fmt.Sprintf(":%s", os.Getenv("BACKEND_PORT")))
+}
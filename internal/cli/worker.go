@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"ThinkBank-backend/internal/ingest"
+	"ThinkBank-backend/internal/queue"
+	"ThinkBank-backend/internal/service"
+	"ThinkBank-backend/internal/trip"
+	"ThinkBank-backend/internal/util"
+	"ThinkBank-backend/internal/vector"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Worker 只运行队列消费者与周期任务，不提供 HTTP。
+// 规范化需要通过 BACKEND_URL 读取原文件，因此仍需有 serve 进程提供静态文件
+func Worker(args []string) error {
+	fs := flag.NewFlagSet("worker", flag.ExitOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	setupDatabase()
+
+	files := newFileServices(nil)
+	modelService := service.NewHTTPModelService(os.Getenv("MODEL_SERVICE_URL"))
+	startWorkers(files, modelService)
+
+	log.Println("Worker started")
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	<-stop
+	log.Println("Worker stopped")
+	return nil
+}
+
+// startWorkers 启动队列消费者与后台任务
+func startWorkers(files fileServices, modelService service.ModelService) {
+	util.ConfigureMetadataPolicy(os.Getenv("NORMALIZE_METADATA"))
+	queue.ConsumeNormalizeFile(3, files.original, files.normalized)
+	queue.ConsumeEmbeddingFile(modelService, 3)
+
+	// 接手其他进程上传、导入或重启前未处理完的文件
+	queue.ScheduleStalledFiles(5*time.Minute, time.Minute)
+
+	// 位置变化后重新计算所在时间段的行程
+	trip.StartRefresher(5*time.Second, time.Minute)
+
+	// 监听目录自动导入
+	startWatcher(files)
+
+	// 后台使用新模型重新生成向量，期间搜索仍使用当前模型
+	if target, ok := vector.TargetModel(); ok {
+		reembedModelService := service.NewHTTPModelService(os.Getenv("REEMBED_MODEL_SERVICE_URL"))
+		queue.ConsumeReembeddingFile(reembedModelService, target, 2)
+		queue.ScheduleReembedding(target, 100, 30*time.Second)
+	}
+}
+
+// startWatcher 按 WATCH_DIRS 配置监听目录，多个目录用逗号分隔
+func startWatcher(files fileServices) {
+	var dirs []string
+	for _, dir := range strings.Split(os.Getenv("WATCH_DIRS"), ",") {
+		if dir = strings.TrimSpace(dir); dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	if len(dirs) == 0 {
+		return
+	}
+
+	watcher := &ingest.Watcher{
+		Dirs:         dirs,
+		Importer:     &ingest.Importer{FileService: files.original, Enqueue: true},
+		Debounce:     envDuration("WATCH_DEBOUNCE", 5*time.Second),
+		PollInterval: envDuration("WATCH_POLL_INTERVAL", 30*time.Second),
+	}
+	watcher.Start()
+}
+
+func envDuration(name string, def time.Duration) time.Duration {
+	val := os.Getenv(name)
+	if val == "" {
+		return def
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil || d <= 0 {
+		log.Fatalf("Invalid %s: %s", name, val)
+	}
+	return d
+}
@@ -15,14 +15,22 @@ func InitPostgres(user, password, dbname, host, port string) {
 		"host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
 		host, user, password, dbname, port,
 	)
-	var err error
-	db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
-	if err != nil {
+	if err := Open(dsn); err != nil {
 		log.Fatal("Failed to connect database:", err)
 	}
 	log.Println("PostgreSQL connected")
 }
 
+// Open 按 DSN 连接数据库，供测试等不经过环境变量配置的场景使用
+func Open(dsn string) error {
+	conn, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	if err != nil {
+		return err
+	}
+	db = conn
+	return nil
+}
+
 func Instance() *gorm.DB {
 	return db
 }
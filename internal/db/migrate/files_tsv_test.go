@@ -0,0 +1,58 @@
+package migrate
+
+import (
+	"ThinkBank-backend/internal/db"
+	"testing"
+)
+
+func TestFilesTSV(t *testing.T) {
+	openTestDB(t)
+
+	// 在事务内执行，结束后回滚
+	tx := db.Instance().Begin()
+	defer tx.Rollback()
+
+	var id uint
+	err := tx.Raw(`
+        INSERT INTO files (file_name, caption, tags, metadata)
+        VALUES ('IMG_0042.jpg', 'A dog running on the beach', '["sunset"]', '{"description":"Bondi holiday"}')
+        RETURNING id
+    `).Scan(&id).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		query string
+		want  bool
+	}{
+		{"dogs", true},      // 描述，按英文词干匹配
+		{"sunset", true},    // 关键词
+		{"bondi", true},     // 导入时的元数据
+		{"img_0042", true},  // 文件名
+		{"mountain", false}, // 没有出现的词
+	}
+	match := func(query string) bool {
+		t.Helper()
+		var n int64
+		err := tx.Raw("SELECT COUNT(*) FROM files WHERE id = ? AND tsv @@ websearch_to_tsquery('english', ?)", id, query).
+			Scan(&n).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n > 0
+	}
+	for _, c := range cases {
+		if got := match(c.query); got != c.want {
+			t.Errorf("match(%q) = %v, want %v", c.query, got, c.want)
+		}
+	}
+
+	// 更新描述后重新生成
+	if err := tx.Exec("UPDATE files SET caption = 'Snowy mountain' WHERE id = ?", id).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !match("mountain") || match("dogs") {
+		t.Error("tsv not refreshed after caption update")
+	}
+}
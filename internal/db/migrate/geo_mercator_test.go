@@ -0,0 +1,104 @@
+package migrate
+
+import (
+	"ThinkBank-backend/internal/db"
+	"math"
+	"os"
+	"testing"
+)
+
+// openTestDB 连接 THINKBANK_TEST_DSN 指定的数据库（需安装 postgis 与 vector）并执行迁移，未设置时跳过
+func openTestDB(t *testing.T) {
+	t.Helper()
+	dsn := os.Getenv("THINKBANK_TEST_DSN")
+	if dsn == "" {
+		t.Skip("THINKBANK_TEST_DSN not set")
+	}
+	if err := db.Open(dsn); err != nil {
+		t.Fatal(err)
+	}
+	if err := Up(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGeoMercator(t *testing.T) {
+	openTestDB(t)
+
+	var mig *Migration
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range migrations {
+		if migrations[i].Name == "geo_mercator" {
+			mig = &migrations[i]
+		}
+	}
+	if mig == nil {
+		t.Fatal("migration geo_mercator not found")
+	}
+
+	// 在事务内执行，结束后回滚
+	tx := db.Instance().Begin()
+	defer tx.Rollback()
+
+	const (
+		byTrigger  = 990000001
+		byBackfill = 990000002
+		polar      = 990000003
+	)
+	exec := func(sql string, args ...interface{}) {
+		t.Helper()
+		if err := tx.Exec(sql, args...).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	point := func(id int) (x, y float64, ok bool) {
+		t.Helper()
+		var rows []struct{ X, Y float64 }
+		err := tx.Raw("SELECT ST_X(geom3857) AS x, ST_Y(geom3857) AS y FROM geos WHERE id = ? AND geom3857 IS NOT NULL", id).
+			Scan(&rows).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) == 0 {
+			return 0, 0, false
+		}
+		return rows[0].X, rows[0].Y, true
+	}
+
+	exec("INSERT INTO geos (id, latitude, longitude, create_at) VALUES (?, 48.8566, 2.3522, now())", byTrigger)
+	exec("INSERT INTO geos (id, latitude, longitude, create_at) VALUES (?, 89.9, 10, now())", polar)
+
+	// 模拟迁移前写入的记录
+	exec("ALTER TABLE geos DISABLE TRIGGER trg_geos_derive_geom")
+	exec("INSERT INTO geos (id, latitude, longitude, create_at) VALUES (?, 41.9028, 12.4964, now())", byBackfill)
+	exec("ALTER TABLE geos ENABLE TRIGGER trg_geos_derive_geom")
+	if _, _, ok := point(byBackfill); ok {
+		t.Fatal("geom3857 populated while trigger disabled")
+	}
+	exec(mig.Up)
+
+	// Web Mercator：x = R·λ，y = R·ln(tan(π/4 + φ/2))
+	const r = 6378137.0
+	cases := []struct {
+		id       int
+		lat, lng float64
+	}{
+		{byTrigger, 48.8566, 2.3522},
+		{byBackfill, 41.9028, 12.4964},
+		{polar, 85.05112878, 10}, // 超出范围的纬度被截断
+	}
+	for _, c := range cases {
+		x, y, ok := point(c.id)
+		if !ok {
+			t.Fatalf("geos %d: geom3857 not populated", c.id)
+		}
+		wantX := r * c.lng * math.Pi / 180
+		wantY := r * math.Log(math.Tan(math.Pi/4+c.lat*math.Pi/360))
+		if math.Abs(x-wantX) > 1 || math.Abs(y-wantY) > 1 {
+			t.Errorf("geos %d: geom3857 = (%f, %f), want (%f, %f)", c.id, x, y, wantX, wantY)
+		}
+	}
+}
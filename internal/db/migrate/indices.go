@@ -2,39 +2,117 @@ package migrate
 
 import (
 	"ThinkBank-backend/internal/db"
+	"ThinkBank-backend/internal/vector"
 	"fmt"
 	"log"
+
+	"gorm.io/gorm"
 )
 
-const maxDegree = 16
-const efConstruction = 200
+// SyncVectorIndices 同步依赖运行配置（度量、模型、HNSW 参数）的向量索引，
+// 无法写成固定的版本化迁移，每次迁移后执行
+func SyncVectorIndices() {
+	err := db.Instance().Transaction(func(tx *gorm.DB) error {
+		if err := lock(tx); err != nil {
+			return err
+		}
+		if err := InitModelIndex(tx, vector.ActiveModel()); err != nil {
+			return err
+		}
+		if target, ok := vector.TargetModel(); ok {
+			if err := InitModelIndex(tx, target); err != nil {
+				return err
+			}
+		}
+		return syncNormalization(tx, vector.NormalizeEnabled())
+	})
+	if err != nil {
+		log.Fatal("Vector index initialization failed:", err)
+	}
+}
 
-func InitIndices() {
+// InitModelIndex 为单个模型建立 HNSW 表达式索引，度量或建索引参数变化时删除旧索引重建
+func InitModelIndex(tx *gorm.DB, m vector.Model) error {
+	opClass := vector.ActiveMetric().OpClass()
+	params := vector.ActiveIndexParams()
+	index := m.IndexName()
 	sql := fmt.Sprintf(`
--- GIN 索引
-ALTER TABLE files ADD COLUMN IF NOT EXISTS tsv tsvector;
-CREATE INDEX IF NOT EXISTS idx_files_tsv
-ON files USING gin(tsv);
-
--- HNSW 索引
 DO $$
 BEGIN
+    IF EXISTS (
+        SELECT 1
+        FROM pg_indexes
+        WHERE schemaname = 'public'
+          AND tablename = 'embeddings'
+          AND indexname = '%[1]s'
+          AND (indexdef NOT LIKE '%%%[2]s%%'
+            OR indexdef NOT LIKE '%%m=''%[3]d''%%'
+            OR indexdef NOT LIKE '%%ef_construction=''%[4]d''%%')
+    ) THEN
+        RAISE NOTICE 'HNSW parameters changed, rebuilding %[1]s';
+        DROP INDEX %[1]s;
+    END IF;
+
     IF NOT EXISTS (
         SELECT 1
         FROM pg_indexes
         WHERE schemaname = 'public'
-          AND tablename = 'files'
-          AND indexname = 'idx_files_vector_hnsw'
+          AND tablename = 'embeddings'
+          AND indexname = '%[1]s'
     ) THEN
-        CREATE INDEX idx_files_vector_hnsw
-        ON files USING hnsw (vector vector_l2_ops)
-        WITH (m = %d, ef_construction = %d);
+        CREATE INDEX %[1]s
+        ON embeddings USING hnsw (%[5]s %[2]s)
+        WITH (m = %[3]d, ef_construction = %[4]d)
+        WHERE %[6]s;
     END IF;
 END$$;
-    `, maxDegree, efConstruction)
+    `, index, opClass, params.M, params.EfConstruction, m.Column(), m.Predicate())
+
+	if err := tx.Exec(sql).Error; err != nil {
+		return fmt.Errorf("HNSW index initialization for %s failed: %w", m.Key(), err)
+	}
+	log.Printf("HNSW index %s initialized", index)
+	return nil
+}
+
+// syncNormalization 开启归一化后只在首次启动时处理存量向量，之后写入的向量已在写入前归一化。
+// 关闭时清除标记，再次开启时重新处理期间写入的向量
+func syncNormalization(tx *gorm.DB, enabled bool) error {
+	var normalized []string
+	if err := tx.Raw("SELECT value FROM vector_settings WHERE name = 'normalized'").Scan(&normalized).Error; err != nil {
+		return err
+	}
+	done := len(normalized) > 0 && normalized[0] == "1"
+	if enabled == done {
+		return nil
+	}
+
+	if enabled {
+		if err := NormalizeVectors(tx); err != nil {
+			return err
+		}
+	}
+	value := "0"
+	if enabled {
+		value = "1"
+	}
+	return tx.Exec(`
+INSERT INTO vector_settings (name, value) VALUES ('normalized', ?)
+ON CONFLICT (name) DO UPDATE SET value = EXCLUDED.value, updated_at = now();
+    `, value).Error
+}
 
-	if err := db.Instance().Exec(sql).Error; err != nil {
-		log.Fatal("GIN / HNSW index initialization failed:", err)
+// NormalizeVectors 将已存储但未归一化的向量归一化
+func NormalizeVectors(tx *gorm.DB) error {
+	result := tx.Exec(`
+UPDATE embeddings
+SET vector = l2_normalize(vector)
+WHERE vector_norm(vector) > 0
+  AND abs(vector_norm(vector) - 1) > 1e-4;
+    `)
+	if result.Error != nil {
+		return fmt.Errorf("vector normalization failed: %w", result.Error)
 	}
-	log.Println("GIN / HNSW index initialized")
+	log.Printf("Normalized %d stored vectors", result.RowsAffected)
+	return nil
 }
@@ -2,21 +2,194 @@ package migrate
 
 import (
 	"ThinkBank-backend/internal/db"
-	"ThinkBank-backend/internal/model"
+	"embed"
+	"fmt"
+	"io/fs"
 	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
 )
 
-// DBMigrateAll 用于迁移所有表结构
-func DBMigrateAll() {
-	log.Println("Starting table migrations")
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// 迁移文件名：<版本>_<名称>.up.sql / <版本>_<名称>.down.sql
+var migrationName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration 一个版本的迁移
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// appliedMigration schema_migrations 中的一行
+type appliedMigration struct {
+	Version   int64
+	Name      string
+	AppliedAt time.Time
+}
+
+// Migrations 读取内嵌的迁移文件，按版本升序
+func Migrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, e := range entries {
+		m := migrationName.FindStringSubmatch(e.Name())
+		if m == nil {
+			return nil, fmt.Errorf("invalid migration file name: %s", e.Name())
+		}
+		version, _ := strconv.ParseInt(m[1], 10, 64)
+		data, err := migrationFiles.ReadFile("migrations/" + e.Name())
+		if err != nil {
+			return nil, err
+		}
 
-	if err := db.Instance().AutoMigrate(&model.File{}); err != nil {
-		log.Fatal("Files table migration failed:", err)
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names: %s, %s", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(data)
+		} else {
+			mig.Down = string(data)
+		}
 	}
 
-	if err := db.Instance().AutoMigrate(&model.Geo{}); err != nil {
-		log.Fatal("Geos table migration failed:", err)
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, *mig)
 	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
 
-	log.Println("Table migrations completed")
+// lock 获取事务级 advisory lock，多个副本同时启动时串行执行迁移，事务结束自动释放
+func lock(tx *gorm.DB) error {
+	if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext('schema_migrations'))").Error; err != nil {
+		return err
+	}
+	return tx.Exec(`
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version    bigint PRIMARY KEY,
+    name       text NOT NULL,
+    applied_at timestamptz NOT NULL DEFAULT now()
+);
+    `).Error
+}
+
+func appliedMigrations(tx *gorm.DB) (map[int64]appliedMigration, error) {
+	var rows []appliedMigration
+	if err := tx.Raw("SELECT version, name, applied_at FROM schema_migrations").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]appliedMigration, len(rows))
+	for _, r := range rows {
+		applied[r.Version] = r
+	}
+	return applied, nil
+}
+
+// Up 依次执行未应用的迁移，每个版本一个事务，失败时该版本整体回滚
+func Up() error {
+	migrations, err := Migrations()
+	if err != nil {
+		return err
+	}
+
+	for _, mig := range migrations {
+		applied := false
+		err := db.Instance().Transaction(func(tx *gorm.DB) error {
+			if err := lock(tx); err != nil {
+				return err
+			}
+			// 拿到锁后重新检查，其他副本可能已经执行过
+			done, err := appliedMigrations(tx)
+			if err != nil {
+				return err
+			}
+			if a, ok := done[mig.Version]; ok {
+				if a.Name != mig.Name {
+					return fmt.Errorf("version %d was applied as %s", mig.Version, a.Name)
+				}
+				return nil
+			}
+			if err := tx.Exec(mig.Up).Error; err != nil {
+				return err
+			}
+			applied = true
+			return tx.Exec("INSERT INTO schema_migrations (version, name) VALUES (?, ?)", mig.Version, mig.Name).Error
+		})
+		if err != nil {
+			return fmt.Errorf("migration %d_%s failed: %w", mig.Version, mig.Name, err)
+		}
+		if applied {
+			log.Printf("Migration %d_%s applied", mig.Version, mig.Name)
+		}
+	}
+	return nil
+}
+
+// Down 回滚最近应用的 steps 个迁移
+func Down(steps int) error {
+	migrations, err := Migrations()
+	if err != nil {
+		return err
+	}
+	byVersion := make(map[int64]Migration, len(migrations))
+	for _, mig := range migrations {
+		byVersion[mig.Version] = mig
+	}
+
+	for i := 0; i < steps; i++ {
+		var reverted *appliedMigration
+		err := db.Instance().Transaction(func(tx *gorm.DB) error {
+			if err := lock(tx); err != nil {
+				return err
+			}
+			var last []appliedMigration
+			err := tx.Raw("SELECT version, name, applied_at FROM schema_migrations ORDER BY version DESC LIMIT 1").
+				Scan(&last).Error
+			if err != nil || len(last) == 0 {
+				return err
+			}
+
+			mig, ok := byVersion[last[0].Version]
+			if !ok {
+				return fmt.Errorf("migration %d_%s is not known to this build", last[0].Version, last[0].Name)
+			}
+			if mig.Down == "" {
+				return fmt.Errorf("migration %d_%s has no down file", mig.Version, mig.Name)
+			}
+			if err := tx.Exec(mig.Down).Error; err != nil {
+				return err
+			}
+			reverted = &last[0]
+			return tx.Exec("DELETE FROM schema_migrations WHERE version = ?", mig.Version).Error
+		})
+		if err != nil {
+			return err
+		}
+		if reverted == nil {
+			log.Println("No migrations to revert")
+			return nil
+		}
+		log.Printf("Migration %d_%s reverted", reverted.Version, reverted.Name)
+	}
+	return nil
 }
@@ -0,0 +1,42 @@
+-- 基线：与原 AutoMigrate + InitExtensions + InitIndices 产生的结构一致，
+-- 全部使用 IF NOT EXISTS，已有数据库执行后只补齐记录。
+-- 基线没有 down 文件，回滚到此为止，不会删除 files 等表
+
+CREATE EXTENSION IF NOT EXISTS vector;
+CREATE EXTENSION IF NOT EXISTS postgis;
+
+CREATE TABLE IF NOT EXISTS files (
+    id                 bigserial PRIMARY KEY,
+    created_at         timestamptz,
+    updated_at         timestamptz,
+    deleted_at         timestamptz,
+    file_name          text,
+    original_file_path text,
+    file_path          text,
+    metadata           jsonb,
+    type               text,
+    caption            text,
+    tags               jsonb,
+    vector             vector(512)
+);
+CREATE INDEX IF NOT EXISTS idx_files_deleted_at ON files (deleted_at);
+
+CREATE TABLE IF NOT EXISTS geos (
+    id        bigint PRIMARY KEY,
+    latitude  decimal NOT NULL,
+    longitude decimal NOT NULL,
+    geom      geometry(Point, 4326),
+    geom3857  geometry(Point, 3857),
+    create_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_geos_id ON geos (id);
+CREATE INDEX IF NOT EXISTS idx_geo_geom_gist ON geos USING gist (geom);
+CREATE INDEX IF NOT EXISTS idx_geo_3857_gist ON geos USING gist (geom3857);
+
+-- 全文检索
+ALTER TABLE files ADD COLUMN IF NOT EXISTS tsv tsvector;
+CREATE INDEX IF NOT EXISTS idx_files_tsv ON files USING gin (tsv);
+
+-- HNSW 索引
+CREATE INDEX IF NOT EXISTS idx_files_vector_hnsw ON files USING hnsw (vector vector_l2_ops)
+WITH (m = 16, ef_construction = 200);
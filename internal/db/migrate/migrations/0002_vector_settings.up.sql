@@ -0,0 +1,6 @@
+-- 记录已应用到存储数据上的向量配置，例如是否已归一化，避免每次启动重复处理
+CREATE TABLE IF NOT EXISTS vector_settings (
+    name       text PRIMARY KEY,
+    value      text NOT NULL,
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
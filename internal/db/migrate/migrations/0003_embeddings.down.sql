@@ -0,0 +1,11 @@
+-- 只能恢复旧默认模型 default:1:512 的向量，其他模型的向量随表删除
+ALTER TABLE files ADD COLUMN IF NOT EXISTS vector vector(512);
+UPDATE files f
+SET vector = e.vector::vector(512)
+FROM embeddings e
+WHERE e.file_id = f.id
+  AND e.model_name = 'default' AND e.model_version = '1' AND e.dimension = 512;
+CREATE INDEX IF NOT EXISTS idx_files_vector_hnsw ON files USING hnsw (vector vector_l2_ops)
+WITH (m = 16, ef_construction = 200);
+
+DROP TABLE IF EXISTS embeddings;
@@ -0,0 +1,23 @@
+-- 向量按模型单独存储，不限定维度，HNSW 表达式索引按模型与配置在迁移后同步
+CREATE TABLE IF NOT EXISTS embeddings (
+    id            bigserial PRIMARY KEY,
+    file_id       bigint NOT NULL,
+    model_name    text NOT NULL,
+    model_version text NOT NULL,
+    dimension     bigint NOT NULL,
+    vector        vector NOT NULL,
+    created_at    timestamptz,
+    updated_at    timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_embeddings_file_model ON embeddings (file_id, model_name, model_version);
+CREATE INDEX IF NOT EXISTS idx_embeddings_model ON embeddings (model_name, model_version);
+
+-- 旧版 files.vector 搬到 embeddings，按旧默认模型 default:1:512 记录
+INSERT INTO embeddings (file_id, model_name, model_version, dimension, vector, created_at, updated_at)
+SELECT id, 'default', '1', 512, vector, now(), now()
+FROM files
+WHERE vector IS NOT NULL
+ON CONFLICT (file_id, model_name, model_version) DO NOTHING;
+
+DROP INDEX IF EXISTS idx_files_vector_hnsw;
+ALTER TABLE files DROP COLUMN IF EXISTS vector;
@@ -0,0 +1,10 @@
+-- 后台重新生成向量失败的记录，超过重试次数的文件不再被选中
+CREATE TABLE IF NOT EXISTS reembedding_failures (
+    file_id       bigint NOT NULL,
+    model_name    text NOT NULL,
+    model_version text NOT NULL,
+    attempts      integer NOT NULL DEFAULT 0,
+    last_error    text,
+    failed_at     timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (file_id, model_name, model_version)
+);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_files_status;
+ALTER TABLE files DROP COLUMN IF EXISTS status;
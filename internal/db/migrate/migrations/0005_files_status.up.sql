@@ -0,0 +1,13 @@
+-- 文件处理状态。已有记录按处理进度回填：已有向量为处理完成，只有规范化文件时等待生成向量
+ALTER TABLE files ADD COLUMN IF NOT EXISTS status text;
+
+UPDATE files
+SET status = CASE
+    WHEN EXISTS (SELECT 1 FROM embeddings e WHERE e.file_id = files.id) THEN 'ready'
+    WHEN file_path <> '' THEN 'normalized'
+    ELSE 'pending'
+END
+WHERE status IS NULL;
+
+ALTER TABLE files ALTER COLUMN status SET DEFAULT 'pending';
+CREATE INDEX IF NOT EXISTS idx_files_status ON files (status);
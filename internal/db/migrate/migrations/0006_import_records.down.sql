@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS import_records;
+DROP INDEX IF EXISTS idx_files_hash_unique;
+DROP INDEX IF EXISTS idx_files_taken_at;
+ALTER TABLE files DROP COLUMN IF EXISTS taken_at;
+ALTER TABLE files DROP COLUMN IF EXISTS hash;
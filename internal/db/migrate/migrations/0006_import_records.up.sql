@@ -0,0 +1,32 @@
+-- 本地目录与 Takeout 导入：内容 hash 去重、导入元数据中的拍摄时间，以及记录已导入的路径用于继续导入
+ALTER TABLE files ADD COLUMN IF NOT EXISTS hash text;
+ALTER TABLE files ADD COLUMN IF NOT EXISTS taken_at timestamptz;
+CREATE INDEX IF NOT EXISTS idx_files_taken_at ON files (taken_at);
+
+-- 未删除文件的内容 hash 唯一，并发上传或导入相同内容时只保留一条记录。
+-- 列已存在时（由早期的 AutoMigrate 创建）可能有空字符串与重复值：空值改为 NULL，
+-- 重复记录保留最早的一条作为去重对象，其余清空 hash 后原样保留
+UPDATE files SET hash = NULL WHERE hash = '';
+WITH dup AS (
+    SELECT id, row_number() OVER (PARTITION BY hash ORDER BY id) AS n
+    FROM files
+    WHERE deleted_at IS NULL AND hash IS NOT NULL
+)
+UPDATE files SET hash = NULL
+FROM dup
+WHERE dup.id = files.id AND dup.n > 1;
+DROP INDEX IF EXISTS idx_files_hash;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_files_hash_unique ON files (hash)
+WHERE deleted_at IS NULL AND hash IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS import_records (
+    id         bigserial PRIMARY KEY,
+    source     text NOT NULL,
+    size       bigint NOT NULL,
+    mod_time   timestamptz NOT NULL,
+    file_id    bigint NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_import_records_source ON import_records (source);
+CREATE INDEX IF NOT EXISTS idx_import_records_file_id ON import_records (file_id);
@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_files_taken_or_created;
+DROP INDEX IF EXISTS idx_files_meta_rating;
+DROP INDEX IF EXISTS idx_files_meta_exposure;
+DROP INDEX IF EXISTS idx_files_meta_aperture;
+DROP INDEX IF EXISTS idx_files_meta_focal35;
+DROP INDEX IF EXISTS idx_files_meta_focal;
+DROP INDEX IF EXISTS idx_files_meta_iso;
+DROP INDEX IF EXISTS idx_files_tags;
+DROP INDEX IF EXISTS idx_files_metadata;
+DROP FUNCTION IF EXISTS metadata_number(jsonb, text);
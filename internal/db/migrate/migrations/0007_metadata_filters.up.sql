@@ -0,0 +1,22 @@
+-- 元数据过滤与分面：字符串字段用 GIN 包含查询，数值字段用表达式索引
+
+-- 非数值内容返回 NULL，避免类型转换失败
+CREATE OR REPLACE FUNCTION metadata_number(metadata jsonb, key text) RETURNS numeric
+LANGUAGE sql IMMUTABLE PARALLEL SAFE AS $$
+    SELECT CASE
+        WHEN metadata->>key ~ '^-?[0-9]+(\.[0-9]+)?$' THEN (metadata->>key)::numeric
+    END
+$$;
+
+CREATE INDEX IF NOT EXISTS idx_files_metadata ON files USING gin (metadata jsonb_path_ops);
+CREATE INDEX IF NOT EXISTS idx_files_tags ON files USING gin (tags jsonb_path_ops);
+
+CREATE INDEX IF NOT EXISTS idx_files_meta_iso ON files (metadata_number(metadata, 'iso'));
+CREATE INDEX IF NOT EXISTS idx_files_meta_focal ON files (metadata_number(metadata, 'focalLength'));
+CREATE INDEX IF NOT EXISTS idx_files_meta_focal35 ON files (metadata_number(metadata, 'focalLength35mm'));
+CREATE INDEX IF NOT EXISTS idx_files_meta_aperture ON files (metadata_number(metadata, 'fNumber'));
+CREATE INDEX IF NOT EXISTS idx_files_meta_exposure ON files (metadata_number(metadata, 'exposureTime'));
+CREATE INDEX IF NOT EXISTS idx_files_meta_rating ON files (metadata_number(metadata, 'rating'));
+
+-- 按年份过滤与统计
+CREATE INDEX IF NOT EXISTS idx_files_taken_or_created ON files ((COALESCE(taken_at, created_at)));
@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS trg_geos_derive_geom ON geos;
+DROP FUNCTION IF EXISTS geos_derive_geom();
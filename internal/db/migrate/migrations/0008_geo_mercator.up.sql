@@ -0,0 +1,22 @@
+-- 由经纬度统一生成 geom 与 geom3857，行程聚类基于 geom3857
+
+CREATE OR REPLACE FUNCTION geos_derive_geom() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+    NEW.geom := ST_SetSRID(ST_MakePoint(NEW.longitude, NEW.latitude), 4326);
+    -- Web Mercator 只覆盖纬度 ±85.05112878°
+    NEW.geom3857 := ST_Transform(
+        ST_SetSRID(ST_MakePoint(NEW.longitude, LEAST(GREATEST(NEW.latitude, -85.05112878), 85.05112878)), 4326),
+        3857
+    );
+    RETURN NEW;
+END
+$$;
+
+DROP TRIGGER IF EXISTS trg_geos_derive_geom ON geos;
+CREATE TRIGGER trg_geos_derive_geom
+BEFORE INSERT OR UPDATE ON geos
+FOR EACH ROW EXECUTE FUNCTION geos_derive_geom();
+
+-- 回填已有记录，触发器会重新计算两列
+UPDATE geos SET latitude = latitude;
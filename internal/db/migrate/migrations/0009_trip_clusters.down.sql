@@ -0,0 +1,6 @@
+DROP TRIGGER IF EXISTS trg_geos_mark_trip_dirty ON geos;
+DROP FUNCTION IF EXISTS geos_mark_trip_dirty();
+DROP TABLE IF EXISTS trip_dirty_periods;
+DROP TABLE IF EXISTS trip_cluster_photos;
+DROP TABLE IF EXISTS trip_clusters;
+DROP INDEX IF EXISTS idx_geos_create_at;
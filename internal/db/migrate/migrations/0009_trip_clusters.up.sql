@@ -0,0 +1,63 @@
+-- 按层级与时间段预先计算的行程聚类，geos 变化时标记所在时间段，由后台任务重新计算
+
+CREATE INDEX IF NOT EXISTS idx_geos_create_at ON geos (create_at);
+
+CREATE TABLE IF NOT EXISTS trip_clusters (
+    id          bigserial PRIMARY KEY,
+    level       text NOT NULL,
+    period      date NOT NULL,
+    radius      double precision NOT NULL,
+    center_lon  double precision NOT NULL,
+    center_lat  double precision NOT NULL,
+    geom3857    geometry NOT NULL,
+    photo_count integer NOT NULL,
+    start_ts    timestamptz NOT NULL,
+    end_ts      timestamptz NOT NULL,
+    updated_at  timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_trip_clusters_level_period ON trip_clusters (level, period);
+CREATE INDEX IF NOT EXISTS idx_trip_clusters_geom ON trip_clusters USING gist (geom3857);
+
+CREATE TABLE IF NOT EXISTS trip_cluster_photos (
+    cluster_id bigint NOT NULL REFERENCES trip_clusters (id) ON DELETE CASCADE,
+    file_id    bigint NOT NULL,
+    PRIMARY KEY (cluster_id, file_id)
+);
+CREATE INDEX IF NOT EXISTS idx_trip_cluster_photos_file_id ON trip_cluster_photos (file_id);
+
+CREATE TABLE IF NOT EXISTS trip_dirty_periods (
+    level     text NOT NULL,
+    period    date NOT NULL,
+    marked_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (level, period)
+);
+
+CREATE OR REPLACE FUNCTION geos_mark_trip_dirty() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        INSERT INTO trip_dirty_periods (level, period)
+        SELECT level, date_trunc(level, NEW.create_at)::date
+        FROM unnest(ARRAY['day', 'week', 'month']) AS level
+        ON CONFLICT DO NOTHING;
+    END IF;
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        INSERT INTO trip_dirty_periods (level, period)
+        SELECT level, date_trunc(level, OLD.create_at)::date
+        FROM unnest(ARRAY['day', 'week', 'month']) AS level
+        ON CONFLICT DO NOTHING;
+    END IF;
+    RETURN NULL;
+END
+$$;
+
+DROP TRIGGER IF EXISTS trg_geos_mark_trip_dirty ON geos;
+CREATE TRIGGER trg_geos_mark_trip_dirty
+AFTER INSERT OR UPDATE OR DELETE ON geos
+FOR EACH ROW EXECUTE FUNCTION geos_mark_trip_dirty();
+
+-- 已有位置全部标记，首次刷新时计算
+INSERT INTO trip_dirty_periods (level, period)
+SELECT DISTINCT level, date_trunc(level, create_at)::date
+FROM geos, unnest(ARRAY['day', 'week', 'month']) AS level
+ON CONFLICT DO NOTHING;
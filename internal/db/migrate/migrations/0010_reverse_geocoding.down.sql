@@ -0,0 +1,12 @@
+ALTER TABLE trip_clusters DROP COLUMN IF EXISTS city;
+ALTER TABLE trip_clusters DROP COLUMN IF EXISTS region;
+ALTER TABLE trip_clusters DROP COLUMN IF EXISTS country;
+DROP TRIGGER IF EXISTS trg_geos_reverse_geocode ON geos;
+DROP FUNCTION IF EXISTS geos_reverse_geocode();
+DROP INDEX IF EXISTS idx_geos_place_tsv;
+ALTER TABLE geos DROP COLUMN IF EXISTS place_tsv;
+ALTER TABLE geos DROP COLUMN IF EXISTS city;
+ALTER TABLE geos DROP COLUMN IF EXISTS region;
+ALTER TABLE geos DROP COLUMN IF EXISTS country;
+DROP FUNCTION IF EXISTS reverse_geocode(double precision, double precision);
+DROP TABLE IF EXISTS geo_places;
@@ -0,0 +1,60 @@
+-- 离线逆地理编码：GeoNames 城市数据导入 geo_places 后，按最近的城市为位置与行程附加国家/地区/城市
+
+CREATE TABLE IF NOT EXISTS geo_places (
+    id           bigint PRIMARY KEY, -- GeoNames geonameid
+    name         text NOT NULL,
+    country_code text NOT NULL,
+    country      text,
+    region       text,
+    population   bigint NOT NULL DEFAULT 0,
+    geom         geometry(Point, 4326) NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_geo_places_geom ON geo_places USING gist (geom);
+
+-- 先按平面距离取若干候选再按球面距离排序，超过 100km 视为无法确定
+CREATE OR REPLACE FUNCTION reverse_geocode(lon double precision, lat double precision,
+    OUT country text, OUT region text, OUT city text)
+LANGUAGE sql STABLE AS $$
+    SELECT p.country, p.region, p.name
+    FROM (
+        SELECT *
+        FROM geo_places
+        ORDER BY geom <-> ST_SetSRID(ST_MakePoint(lon, lat), 4326)
+        LIMIT 8
+    ) p
+    WHERE ST_DWithin(p.geom::geography, ST_SetSRID(ST_MakePoint(lon, lat), 4326)::geography, 100000)
+    ORDER BY ST_Distance(p.geom::geography, ST_SetSRID(ST_MakePoint(lon, lat), 4326)::geography)
+    LIMIT 1
+$$;
+
+ALTER TABLE geos ADD COLUMN IF NOT EXISTS country text;
+ALTER TABLE geos ADD COLUMN IF NOT EXISTS region text;
+ALTER TABLE geos ADD COLUMN IF NOT EXISTS city text;
+ALTER TABLE geos ADD COLUMN IF NOT EXISTS place_tsv tsvector GENERATED ALWAYS AS (
+    to_tsvector('english', coalesce(city, '') || ' ' || coalesce(region, '') || ' ' || coalesce(country, ''))
+) STORED;
+CREATE INDEX IF NOT EXISTS idx_geos_place_tsv ON geos USING gin (place_tsv);
+
+-- 触发器按名称顺序执行，位于 trg_geos_derive_geom 之后
+CREATE OR REPLACE FUNCTION geos_reverse_geocode() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+    IF TG_OP = 'INSERT'
+        OR NEW.latitude IS DISTINCT FROM OLD.latitude
+        OR NEW.longitude IS DISTINCT FROM OLD.longitude THEN
+        SELECT r.country, r.region, r.city
+        INTO NEW.country, NEW.region, NEW.city
+        FROM reverse_geocode(NEW.longitude, NEW.latitude) r;
+    END IF;
+    RETURN NEW;
+END
+$$;
+
+DROP TRIGGER IF EXISTS trg_geos_reverse_geocode ON geos;
+CREATE TRIGGER trg_geos_reverse_geocode
+BEFORE INSERT OR UPDATE ON geos
+FOR EACH ROW EXECUTE FUNCTION geos_reverse_geocode();
+
+ALTER TABLE trip_clusters ADD COLUMN IF NOT EXISTS country text;
+ALTER TABLE trip_clusters ADD COLUMN IF NOT EXISTS region text;
+ALTER TABLE trip_clusters ADD COLUMN IF NOT EXISTS city text;
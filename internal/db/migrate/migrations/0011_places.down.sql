@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS trg_places_mark_trip_dirty ON places;
+DROP FUNCTION IF EXISTS places_mark_trip_dirty();
+DROP TABLE IF EXISTS places;
@@ -0,0 +1,42 @@
+-- 用户定义的地点（多边形或圆形范围），可用于过滤照片、从行程中排除，以及分享时去除定位
+
+CREATE TABLE IF NOT EXISTS places (
+    id                 bigserial PRIMARY KEY,
+    name               text NOT NULL UNIQUE,
+    latitude           double precision, -- 圆形范围的中心与半径（米），多边形时为空
+    longitude          double precision,
+    radius             double precision,
+    geom               geometry(MultiPolygon, 4326) NOT NULL,
+    exclude_from_trips boolean NOT NULL DEFAULT true,
+    private            boolean NOT NULL DEFAULT false,
+    created_at         timestamptz NOT NULL DEFAULT now(),
+    updated_at         timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_places_geom ON places USING gist (geom);
+
+-- 排除范围变化后，标记范围内位置所在的时间段重新计算行程
+CREATE OR REPLACE FUNCTION places_mark_trip_dirty() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+    IF TG_OP IN ('INSERT', 'UPDATE') AND NEW.exclude_from_trips THEN
+        INSERT INTO trip_dirty_periods (level, period)
+        SELECT DISTINCT level, date_trunc(level, g.create_at)::date
+        FROM geos g, unnest(ARRAY['day', 'week', 'month']) AS level
+        WHERE ST_Intersects(g.geom, NEW.geom)
+        ON CONFLICT DO NOTHING;
+    END IF;
+    IF TG_OP IN ('UPDATE', 'DELETE') AND OLD.exclude_from_trips THEN
+        INSERT INTO trip_dirty_periods (level, period)
+        SELECT DISTINCT level, date_trunc(level, g.create_at)::date
+        FROM geos g, unnest(ARRAY['day', 'week', 'month']) AS level
+        WHERE ST_Intersects(g.geom, OLD.geom)
+        ON CONFLICT DO NOTHING;
+    END IF;
+    RETURN NULL;
+END
+$$;
+
+DROP TRIGGER IF EXISTS trg_places_mark_trip_dirty ON places;
+CREATE TRIGGER trg_places_mark_trip_dirty
+AFTER INSERT OR UPDATE OR DELETE ON places
+FOR EACH ROW EXECUTE FUNCTION places_mark_trip_dirty();
@@ -0,0 +1,2 @@
+-- 数据修复，无需回滚
+SELECT 1;
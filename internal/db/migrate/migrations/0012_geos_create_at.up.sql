@@ -0,0 +1,10 @@
+-- 拍摄时间未知时位置曾以零值 0001-01-01 写入，改为文件的拍摄时间或上传时间。
+-- 触发器会标记新旧时间段，零值所在的时间段及其行程没有意义，直接删除
+UPDATE geos g
+SET create_at = COALESCE(f.taken_at, f.created_at)
+FROM files f
+WHERE f.id = g.id
+  AND (g.create_at IS NULL OR g.create_at < '0002-01-01 00:00:00+00');
+
+DELETE FROM trip_dirty_periods WHERE period < '0002-01-01';
+DELETE FROM trip_clusters WHERE period < '0002-01-01';
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS trg_files_update_tsv ON files;
+DROP FUNCTION IF EXISTS files_update_tsv();
+UPDATE files SET tsv = NULL;
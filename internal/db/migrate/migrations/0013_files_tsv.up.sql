@@ -0,0 +1,25 @@
+-- 全文检索的 tsv 由触发器根据描述、关键词、标题等字段生成，文件名按原样分词
+CREATE OR REPLACE FUNCTION files_update_tsv() RETURNS trigger
+LANGUAGE plpgsql AS $$
+DECLARE
+    tag_text text;
+BEGIN
+    IF jsonb_typeof(NEW.tags) = 'array' THEN
+        SELECT string_agg(t, ' ') INTO tag_text FROM jsonb_array_elements_text(NEW.tags) AS t;
+    END IF;
+    NEW.tsv :=
+        setweight(to_tsvector('english', concat_ws(' ', NEW.caption, tag_text)), 'A') ||
+        setweight(to_tsvector('english', concat_ws(' ',
+            NEW.metadata->>'title', NEW.metadata->>'description', NEW.metadata->>'keywords')), 'B') ||
+        setweight(to_tsvector('simple', coalesce(NEW.file_name, '')), 'C');
+    RETURN NEW;
+END
+$$;
+
+DROP TRIGGER IF EXISTS trg_files_update_tsv ON files;
+CREATE TRIGGER trg_files_update_tsv
+BEFORE INSERT OR UPDATE OF file_name, caption, tags, metadata ON files
+FOR EACH ROW EXECUTE FUNCTION files_update_tsv();
+
+-- 回填已有记录
+UPDATE files SET caption = caption;
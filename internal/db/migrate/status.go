@@ -0,0 +1,63 @@
+package migrate
+
+import (
+	"ThinkBank-backend/internal/db"
+	"ThinkBank-backend/internal/vector"
+	"fmt"
+	"time"
+)
+
+// Status 单项迁移的状态
+type Status struct {
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+}
+
+// CheckStatus 列出各版本迁移是否已应用，以及按配置同步的向量索引是否存在
+func CheckStatus() ([]Status, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int64]appliedMigration)
+	if db.Instance().Migrator().HasTable("schema_migrations") {
+		if applied, err = appliedMigrations(db.Instance()); err != nil {
+			return nil, err
+		}
+	}
+
+	var statuses []Status
+	for _, mig := range migrations {
+		s := Status{Name: fmt.Sprintf("%04d_%s", mig.Version, mig.Name)}
+		if a, ok := applied[mig.Version]; ok {
+			s.Applied = true
+			s.AppliedAt = &a.AppliedAt
+			delete(applied, mig.Version)
+		}
+		statuses = append(statuses, s)
+	}
+	// 数据库中存在但当前版本不认识的迁移，通常是用更新的版本执行过
+	for _, a := range applied {
+		at := a.AppliedAt
+		statuses = append(statuses, Status{Name: fmt.Sprintf("%04d_%s (unknown)", a.Version, a.Name), Applied: true, AppliedAt: &at})
+	}
+
+	indices := []string{vector.ActiveModel().IndexName()}
+	if target, ok := vector.TargetModel(); ok {
+		indices = append(indices, target.IndexName())
+	}
+	for _, index := range indices {
+		var count int64
+		err := db.Instance().Raw(
+			"SELECT COUNT(*) FROM pg_indexes WHERE schemaname = 'public' AND indexname = ?", index,
+		).Scan(&count).Error
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, Status{Name: "index " + index, Applied: count > 0})
+	}
+
+	return statuses, nil
+}
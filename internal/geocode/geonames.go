@@ -0,0 +1,188 @@
+package geocode
+
+import (
+	"ThinkBank-backend/internal/db"
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+const loadBatchSize = 5000
+
+// Sources GeoNames 导出文件，Admin1 与 Countries 可为空，此时对应名称留空或使用国家代码
+type Sources struct {
+	Cities    string // cities500.txt / cities15000.txt 等
+	Admin1    string // admin1CodesASCII.txt
+	Countries string // countryInfo.txt
+}
+
+// Load 用 GeoNames 城市数据替换 geo_places，返回导入的城市数量
+func Load(src Sources) (int, error) {
+	regions := map[string]string{}
+	if src.Admin1 != "" {
+		// JP.26	Kyoto	Kyoto	1857907
+		err := readTSV(src.Admin1, func(cols []string) error {
+			if len(cols) >= 2 {
+				regions[cols[0]] = cols[1]
+			}
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+	countries := map[string]string{}
+	if src.Countries != "" {
+		// JP	JPN	392	JA	Japan	...
+		err := readTSV(src.Countries, func(cols []string) error {
+			if len(cols) >= 5 {
+				countries[cols[0]] = cols[4]
+			}
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	total := 0
+	err := db.Instance().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("TRUNCATE geo_places").Error; err != nil {
+			return err
+		}
+
+		var b placeBatch
+		err := readTSV(src.Cities, func(cols []string) error {
+			// geonameid, name, asciiname, alternatenames, latitude, longitude, feature class,
+			// feature code, country code, cc2, admin1 code, ..., population, ...
+			if len(cols) < 15 {
+				return fmt.Errorf("unexpected GeoNames row with %d columns", len(cols))
+			}
+			id, err := strconv.ParseInt(cols[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid geonameid %q", cols[0])
+			}
+			lat, err1 := strconv.ParseFloat(cols[4], 64)
+			lon, err2 := strconv.ParseFloat(cols[5], 64)
+			if err1 != nil || err2 != nil {
+				return fmt.Errorf("invalid coordinates for geonameid %d", id)
+			}
+			population, _ := strconv.ParseInt(cols[14], 10, 64)
+
+			country := countries[cols[8]]
+			if country == "" {
+				country = cols[8]
+			}
+			b.add(id, cols[1], cols[8], country, regions[cols[8]+"."+cols[10]], population, lon, lat)
+			if len(b.ids) >= loadBatchSize {
+				if err := b.flush(tx); err != nil {
+					return err
+				}
+			}
+			total++
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return b.flush(tx)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// Backfill 按当前 geo_places 重新解析已有位置的地名，只更新发生变化的行，返回更新的数量。
+// geos 的更新会标记所在时间段，行程的地名随后台刷新一起更新
+func Backfill() (int64, error) {
+	res := db.Instance().Exec(`
+        WITH resolved AS (
+            SELECT g.id, r.country, r.region, r.city
+            FROM geos g
+            CROSS JOIN LATERAL reverse_geocode(g.longitude, g.latitude) r
+        )
+        UPDATE geos g
+        SET country = r.country, region = r.region, city = r.city
+        FROM resolved r
+        WHERE g.id = r.id
+          AND (g.country, g.region, g.city) IS DISTINCT FROM (r.country, r.region, r.city)
+    `)
+	return res.RowsAffected, res.Error
+}
+
+// placeBatch 按列缓存一批城市，用 unnest 一次写入
+type placeBatch struct {
+	ids          pq.Int64Array
+	names        pq.StringArray
+	countryCodes pq.StringArray
+	countries    pq.StringArray
+	regions      pq.StringArray
+	populations  pq.Int64Array
+	lons         pq.Float64Array
+	lats         pq.Float64Array
+}
+
+func (b *placeBatch) add(id int64, name, countryCode, country, region string, population int64, lon, lat float64) {
+	b.ids = append(b.ids, id)
+	b.names = append(b.names, name)
+	b.countryCodes = append(b.countryCodes, countryCode)
+	b.countries = append(b.countries, country)
+	b.regions = append(b.regions, region)
+	b.populations = append(b.populations, population)
+	b.lons = append(b.lons, lon)
+	b.lats = append(b.lats, lat)
+}
+
+func (b *placeBatch) flush(tx *gorm.DB) error {
+	if len(b.ids) == 0 {
+		return nil
+	}
+	err := tx.Exec(`
+        INSERT INTO geo_places (id, name, country_code, country, region, population, geom)
+        SELECT id, name, country_code, country, NULLIF(region, ''), population,
+               ST_SetSRID(ST_MakePoint(lon, lat), 4326)
+        FROM unnest(?::bigint[], ?::text[], ?::text[], ?::text[], ?::text[], ?::bigint[], ?::float8[], ?::float8[])
+            AS t(id, name, country_code, country, region, population, lon, lat)
+        ON CONFLICT (id) DO NOTHING
+    `, b.ids, b.names, b.countryCodes, b.countries, b.regions, b.populations, b.lons, b.lats).Error
+	*b = placeBatch{}
+	return err
+}
+
+// readTSV 逐行读取制表符分隔的文件，跳过空行与 # 开头的注释
+func readTSV(path string, fn func(cols []string) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			log.Printf("Failed to close %s: %v", path, err)
+		}
+	}()
+
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+		if line != "" && !strings.HasPrefix(line, "#") {
+			if ferr := fn(strings.Split(line, "\t")); ferr != nil {
+				return ferr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
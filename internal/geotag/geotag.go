@@ -0,0 +1,146 @@
+package geotag
+
+import (
+	"ThinkBank-backend/internal/db"
+	"ThinkBank-backend/internal/model"
+	"ThinkBank-backend/internal/trip"
+	"errors"
+	"time"
+
+	"github.com/restayway/gogis"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrFileNotFound 文件不存在或已删除
+var ErrFileNotFound = errors.New("file not found")
+
+// DefaultMaxGap 照片与轨迹点允许的最大时间差
+const DefaultMaxGap = 30 * time.Minute
+
+// ValidLocation 经纬度是否在有效范围内
+func ValidLocation(lat, lng float64) bool {
+	return lat >= -90 && lat <= 90 && lng >= -180 && lng <= 180
+}
+
+// SetLocation 设置或修正文件的位置，位置时间取拍摄时间，缺失时取上传时间。
+// 手动设置的位置不会被之后规范化时读取的 EXIF 覆盖
+func SetLocation(id uint, lat, lng float64) (*model.Geo, error) {
+	if !ValidLocation(lat, lng) {
+		return nil, errors.New("latitude must be within [-90, 90] and longitude within [-180, 180]")
+	}
+	var f model.File
+	if err := db.Instance().First(&f, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrFileNotFound
+		}
+		return nil, err
+	}
+
+	createAt := f.CreatedAt
+	if f.TakenAt != nil {
+		createAt = *f.TakenAt
+	}
+	record := &model.Geo{
+		ID:        id,
+		Latitude:  lat,
+		Longitude: lng,
+		Geom:      gogis.Point{Lat: lat, Lng: lng},
+		CreateAt:  createAt,
+	}
+	if err := saveGeos([]*model.Geo{record}); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// ClearLocation 删除文件的位置
+func ClearLocation(id uint) error {
+	if err := db.Instance().Delete(&model.Geo{}, id).Error; err != nil {
+		return err
+	}
+	trip.Notify()
+	return nil
+}
+
+// Options 按轨迹为照片定位的参数
+type Options struct {
+	// Offset 加到照片拍摄时间上得到 GPS 时间，用于修正相机时钟或时区，
+	// 例如相机慢了 2 分钟时为 2m，相机按 UTC+8 记录但被当作 UTC 时为 -8h
+	Offset    time.Duration
+	MaxGap    time.Duration // 0 时使用 DefaultMaxGap
+	Overwrite bool          // 是否覆盖已有位置，默认只处理没有位置的照片
+	IDs       []uint        // 只处理这些文件，为空时处理轨迹时间范围内的全部图片
+	DryRun    bool          // 只返回匹配结果，不写入
+}
+
+// Match 一张照片的定位结果
+type Match struct {
+	ID        uint      `json:"id"`
+	TakenAt   time.Time `json:"takenAt"`
+	Latitude  float64   `json:"latitude"`
+	Longitude float64   `json:"longitude"`
+}
+
+// Result 定位统计
+type Result struct {
+	Candidates int     `json:"candidates"` // 拍摄时间落在轨迹范围内的照片数
+	Matched    []Match `json:"matched"`
+	Unmatched  []uint  `json:"unmatched"` // 附近没有足够近的轨迹点
+}
+
+// ApplyTrack 按拍摄时间在轨迹中查找位置并写入 geos
+func ApplyTrack(track Track, opts Options) (Result, error) {
+	if opts.MaxGap <= 0 {
+		opts.MaxGap = DefaultMaxGap
+	}
+
+	// 拍摄时间 + Offset 需落在轨迹前后 MaxGap 的范围内
+	q := db.Instance().Model(&model.File{}).
+		Where("type = ? AND taken_at IS NOT NULL", "image").
+		Where("taken_at >= ? AND taken_at <= ?",
+			track.Start().Add(-opts.MaxGap-opts.Offset), track.End().Add(opts.MaxGap-opts.Offset))
+	if len(opts.IDs) > 0 {
+		q = q.Where("id IN ?", opts.IDs)
+	}
+	if !opts.Overwrite {
+		q = q.Where("NOT EXISTS (SELECT 1 FROM geos WHERE geos.id = files.id)")
+	}
+	var files []model.File
+	if err := q.Order("taken_at").Find(&files).Error; err != nil {
+		return Result{}, err
+	}
+
+	result := Result{Candidates: len(files), Matched: []Match{}, Unmatched: []uint{}}
+	var records []*model.Geo
+	for _, f := range files {
+		lat, lng, ok := track.Locate(f.TakenAt.Add(opts.Offset), opts.MaxGap)
+		if !ok {
+			result.Unmatched = append(result.Unmatched, f.ID)
+			continue
+		}
+		result.Matched = append(result.Matched, Match{ID: f.ID, TakenAt: *f.TakenAt, Latitude: lat, Longitude: lng})
+		records = append(records, &model.Geo{
+			ID:        f.ID,
+			Latitude:  lat,
+			Longitude: lng,
+			Geom:      gogis.Point{Lat: lat, Lng: lng},
+			CreateAt:  *f.TakenAt,
+		})
+	}
+
+	if opts.DryRun || len(records) == 0 {
+		return result, nil
+	}
+	return result, saveGeos(records)
+}
+
+// saveGeos 写入或覆盖位置
+func saveGeos(records []*model.Geo) error {
+	err := db.Instance().Clauses(clause.OnConflict{UpdateAll: true}).CreateInBatches(records, 500).Error
+	if err != nil {
+		return err
+	}
+	trip.Notify()
+	return nil
+}
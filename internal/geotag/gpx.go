@@ -0,0 +1,129 @@
+package geotag
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TrackPoint 轨迹上带时间的位置
+type TrackPoint struct {
+	Latitude  float64
+	Longitude float64
+	Time      time.Time
+}
+
+// Track 按时间排序的轨迹点
+type Track []TrackPoint
+
+// 坐标按字符串读取，单个点的坐标缺失或格式错误时只跳过该点
+type gpxPoint struct {
+	Lat  string `xml:"lat,attr"`
+	Lon  string `xml:"lon,attr"`
+	Time string `xml:"time"`
+}
+
+type gpxFile struct {
+	Tracks []struct {
+		Segments []struct {
+			Points []gpxPoint `xml:"trkpt"`
+		} `xml:"trkseg"`
+	} `xml:"trk"`
+}
+
+// ParseGPX 读取 GPX 中所有带时间且坐标有效的轨迹点，多个轨迹与分段合并后按时间排序
+func ParseGPX(r io.Reader) (Track, error) {
+	var doc gpxFile
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	var track Track
+	for _, trk := range doc.Tracks {
+		for _, seg := range trk.Segments {
+			for _, p := range seg.Points {
+				t, err := time.Parse(time.RFC3339, p.Time)
+				if err != nil {
+					continue
+				}
+				lat, lon, ok := parsePoint(p.Lat, p.Lon)
+				if !ok {
+					continue
+				}
+				track = append(track, TrackPoint{Latitude: lat, Longitude: lon, Time: t})
+			}
+		}
+	}
+	if len(track) == 0 {
+		return nil, errors.New("no valid timestamped track points in GPX")
+	}
+	sort.Slice(track, func(i, j int) bool { return track[i].Time.Before(track[j].Time) })
+	return track, nil
+}
+
+// parsePoint 解析并校验坐标范围，NaN 等非法值同样视为无效
+func parsePoint(latStr, lonStr string) (float64, float64, bool) {
+	lat, err := strconv.ParseFloat(strings.TrimSpace(latStr), 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	lon, err := strconv.ParseFloat(strings.TrimSpace(lonStr), 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	if !(lat >= -90 && lat <= 90 && lon >= -180 && lon <= 180) {
+		return 0, 0, false
+	}
+	return lat, lon, true
+}
+
+// Start 第一个点的时间
+func (t Track) Start() time.Time {
+	return t[0].Time
+}
+
+// End 最后一个点的时间
+func (t Track) End() time.Time {
+	return t[len(t)-1].Time
+}
+
+// Locate 估计 at 时刻的位置：前后两点间隔不超过 maxGap 时线性插值，
+// 否则取 maxGap 以内最近的点，都不满足时返回 false
+func (t Track) Locate(at time.Time, maxGap time.Duration) (float64, float64, bool) {
+	i := sort.Search(len(t), func(i int) bool { return !t[i].Time.Before(at) })
+
+	if i < len(t) && t[i].Time.Equal(at) {
+		return t[i].Latitude, t[i].Longitude, true
+	}
+	if i > 0 && i < len(t) {
+		prev, next := t[i-1], t[i]
+		if gap := next.Time.Sub(prev.Time); gap <= maxGap {
+			frac := float64(at.Sub(prev.Time)) / float64(gap)
+			return prev.Latitude + (next.Latitude-prev.Latitude)*frac,
+				prev.Longitude + (next.Longitude-prev.Longitude)*frac, true
+		}
+	}
+
+	var nearest *TrackPoint
+	var best time.Duration
+	for _, j := range []int{i - 1, i} {
+		if j < 0 || j >= len(t) {
+			continue
+		}
+		d := t[j].Time.Sub(at)
+		if d < 0 {
+			d = -d
+		}
+		if d <= maxGap && (nearest == nil || d < best) {
+			nearest, best = &t[j], d
+		}
+	}
+	if nearest == nil {
+		return 0, 0, false
+	}
+	return nearest.Latitude, nearest.Longitude, true
+}
@@ -0,0 +1,91 @@
+package geotag
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseGPX(t *testing.T) {
+	gpx := `<?xml version="1.0"?>
+<gpx version="1.1" xmlns="http://www.topografix.com/GPX/1/1">
+  <trk><trkseg>
+    <trkpt lat="2" lon="20"><time>2023-07-14T10:02:00Z</time></trkpt>
+    <trkpt lat="9" lon="90"></trkpt>
+    <trkpt lat="91" lon="10"><time>2023-07-14T10:01:30Z</time></trkpt>
+    <trkpt lat="1" lon="-180.5"><time>2023-07-14T10:01:40Z</time></trkpt>
+    <trkpt lat="abc" lon="10"><time>2023-07-14T10:01:50Z</time></trkpt>
+    <trkpt lat="NaN" lon="10"><time>2023-07-14T10:01:55Z</time></trkpt>
+    <trkpt lon="10"><time>2023-07-14T10:01:58Z</time></trkpt>
+    <trkpt lat="1" lon="10"><time>2023-07-14T10:01:00Z</time></trkpt>
+  </trkseg></trk>
+  <trk><trkseg>
+    <trkpt lat="3" lon="30"><time>2023-07-14T12:03:00+02:00</time></trkpt>
+  </trkseg></trk>
+</gpx>`
+	track, err := ParseGPX(strings.NewReader(gpx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 没有时间或坐标无效的点被跳过，其余按时间排序
+	wantLats := []float64{1, 2, 3}
+	if len(track) != len(wantLats) {
+		t.Fatalf("len(track) = %d, want %d", len(track), len(wantLats))
+	}
+	for i, want := range wantLats {
+		if track[i].Latitude != want {
+			t.Errorf("track[%d].Latitude = %v, want %v", i, track[i].Latitude, want)
+		}
+	}
+	if want := time.Date(2023, 7, 14, 10, 3, 0, 0, time.UTC); !track.End().Equal(want) {
+		t.Errorf("End() = %v, want %v", track.End(), want)
+	}
+
+	for _, bad := range []string{
+		`<gpx><trk><trkseg><trkpt lat="1" lon="1"></trkpt></trkseg></trk></gpx>`,
+		`<gpx><trk>`,
+		`<gpx><trk><trkseg><trkpt lat="95" lon="1"><time>2023-07-14T10:00:00Z</time></trkpt></trkseg></trk></gpx>`,
+	} {
+		if _, err := ParseGPX(strings.NewReader(bad)); err == nil {
+			t.Errorf("ParseGPX(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestTrackLocate(t *testing.T) {
+	base := time.Date(2023, 7, 14, 10, 0, 0, 0, time.UTC)
+	track := Track{
+		{Latitude: 0, Longitude: 0, Time: base},
+		{Latitude: 10, Longitude: 20, Time: base.Add(10 * time.Minute)},
+		{Latitude: 50, Longitude: 50, Time: base.Add(2 * time.Hour)},
+	}
+	maxGap := 15 * time.Minute
+
+	cases := []struct {
+		name   string
+		at     time.Time
+		want   [2]float64
+		wantOK bool
+	}{
+		{"exact point", base.Add(10 * time.Minute), [2]float64{10, 20}, true},
+		{"interpolated", base.Add(5 * time.Minute), [2]float64{5, 10}, true},
+		{"gap too large uses nearest", base.Add(20 * time.Minute), [2]float64{10, 20}, true},
+		{"gap too large and no point near", base.Add(time.Hour), [2]float64{}, false},
+		{"before start within gap", base.Add(-10 * time.Minute), [2]float64{0, 0}, true},
+		{"before start beyond gap", base.Add(-time.Hour), [2]float64{}, false},
+		{"after end within gap", base.Add(2*time.Hour + maxGap), [2]float64{50, 50}, true},
+		{"after end beyond gap", base.Add(3 * time.Hour), [2]float64{}, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			lat, lng, ok := track.Locate(c.at, maxGap)
+			if ok != c.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, c.wantOK)
+			}
+			if ok && (math.Abs(lat-c.want[0]) > 1e-9 || math.Abs(lng-c.want[1]) > 1e-9) {
+				t.Errorf("Locate = (%v, %v), want %v", lat, lng, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package ingest
+
+import (
+	"ThinkBank-backend/internal/db"
+	"ThinkBank-backend/internal/model"
+	"ThinkBank-backend/internal/queue"
+	"ThinkBank-backend/internal/service"
+	"ThinkBank-backend/internal/util"
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Stats 导入统计
+type Stats struct {
+	Imported   int
+	Duplicates int
+	Skipped    int // 已导入过，或不支持的文件类型
+	Failed     int
+}
+
+// Importer 从本地目录批量导入
+type Importer struct {
+	FileService service.FileService
+	DryRun      bool
+	// Enqueue 导入后在本进程排队规范化，队列满时等待。命令行导入的进程内没有消费者，
+	// 不排队，由 serve / worker 的 ScheduleStalledFiles 接手
+	Enqueue bool
+}
+
+// ImportDir 遍历目录导入所有支持的文件。已记录在 import_records 中且大小、修改时间
+// 未变化的文件会被跳过，因此中断后重新执行即可继续
+func (im *Importer) ImportDir(root string) (Stats, error) {
+	var stats Stats
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || isSidecar(path) {
+			return nil
+		}
+
+		imported, duplicate, err := im.ImportPath(path)
+		switch {
+		case err != nil:
+			stats.Failed++
+			log.Printf("Failed to import %s: %v", path, err)
+		case duplicate:
+			stats.Duplicates++
+		case imported:
+			stats.Imported++
+		default:
+			stats.Skipped++
+		}
+		return nil
+	})
+	return stats, err
+}
+
+// ImportPath 导入单个文件，返回是否新导入以及是否与已有文件内容重复
+func (im *Importer) ImportPath(path string) (imported, duplicate bool, err error) {
+	if util.GetFileType(path) == "unknown" {
+		return false, false, nil
+	}
+
+	source, err := filepath.Abs(path)
+	if err != nil {
+		return false, false, err
+	}
+	info, err := os.Stat(source)
+	if err != nil {
+		return false, false, err
+	}
+
+	done, err := alreadyImported(source, info)
+	if err != nil || done {
+		return false, false, err
+	}
+
+	meta, err := readSidecar(source)
+	if err != nil {
+		log.Printf("Ignoring unreadable sidecar for %s: %v", source, err)
+		meta = nil
+	}
+
+	if im.DryRun {
+		log.Println("Would import:", source)
+		return true, false, nil
+	}
+
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return false, false, err
+	}
+	record, duplicate, err := store(filepath.Base(source), data, im.FileService, meta)
+	if err != nil {
+		return false, false, err
+	}
+	if im.Enqueue && !duplicate {
+		queue.ProduceNormalizeFileWait(record.ID, record.OriginalFilePath)
+	}
+
+	if err := recordImport(source, info, record.ID); err != nil {
+		return false, false, err
+	}
+	if !duplicate {
+		log.Printf("Imported %s as file %d", source, record.ID)
+	}
+	return !duplicate, duplicate, nil
+}
+
+// alreadyImported 相同路径、大小与修改时间视为已导入
+func alreadyImported(source string, info os.FileInfo) (bool, error) {
+	var record model.ImportRecord
+	err := db.Instance().Where("source = ?", source).First(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return record.Size == info.Size() && record.ModTime.Equal(info.ModTime().UTC().Truncate(time.Microsecond)), nil
+}
+
+func recordImport(source string, info os.FileInfo, fileID uint) error {
+	record := &model.ImportRecord{
+		Source: source,
+		Size:   info.Size(),
+		// PostgreSQL 时间戳精度为微秒
+		ModTime: info.ModTime().UTC().Truncate(time.Microsecond),
+		FileID:  fileID,
+	}
+	return db.Instance().Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "source"}},
+		DoUpdates: clause.AssignmentColumns([]string{"size", "mod_time", "file_id", "updated_at"}),
+	}).Create(record).Error
+}
@@ -0,0 +1,112 @@
+package ingest
+
+import (
+	"ThinkBank-backend/internal/db"
+	"ThinkBank-backend/internal/db/migrate"
+	"ThinkBank-backend/internal/model"
+	"ThinkBank-backend/internal/service"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// openTestDB 连接 THINKBANK_TEST_DSN 指定的数据库（需安装 postgis 与 vector）并执行迁移，未设置时跳过
+func openTestDB(t *testing.T) {
+	t.Helper()
+	dsn := os.Getenv("THINKBANK_TEST_DSN")
+	if dsn == "" {
+		t.Skip("THINKBANK_TEST_DSN not set")
+	}
+	if err := db.Open(dsn); err != nil {
+		t.Fatal(err)
+	}
+	if err := migrate.Up(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestImportDuplicateWithSidecar(t *testing.T) {
+	openTestDB(t)
+
+	dir := t.TempDir()
+	im := &Importer{FileService: service.NewLocalFileService(nil, "", "/files", filepath.Join(dir, "store"))}
+	src := filepath.Join(dir, "takeout")
+	if err := os.MkdirAll(src, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	// 内容唯一，避免与库中已有文件重复
+	data := []byte(fmt.Sprintf("not really a jpeg %d", time.Now().UnixNano()))
+	for _, name := range []string{"IMG_1.jpg", "IMG_1(1).jpg"} {
+		if err := os.WriteFile(filepath.Join(src, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sidecars := map[string]string{
+		"IMG_1.jpg.json":    `{"description":"Harbour at dusk","photoTakenTime":{"timestamp":"1689330030"}}`,
+		"IMG_1.jpg(1).json": `{"description":"Harbour copy"}`,
+	}
+	for name, content := range sidecars {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Cleanup(func() {
+		var ids []uint
+		db.Instance().Model(&model.ImportRecord{}).Where("source LIKE ?", src+"%").Pluck("file_id", &ids)
+		db.Instance().Where("source LIKE ?", src+"%").Delete(&model.ImportRecord{})
+		db.Instance().Unscoped().Where("id IN ?", ids).Delete(&model.File{})
+	})
+
+	cases := []struct {
+		name  string
+		path  string
+		reset bool // 删除导入记录，模拟中断后重新导入
+		want  Stats
+	}{
+		{name: "first import", path: "IMG_1.jpg", want: Stats{Imported: 1}},
+		{name: "already imported", path: "IMG_1.jpg", want: Stats{Skipped: 1}},
+		{name: "same content under another name", path: "IMG_1(1).jpg", want: Stats{Duplicates: 1}},
+		{name: "resume after lost import record", path: "IMG_1.jpg", reset: true, want: Stats{Duplicates: 1}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			path := filepath.Join(src, c.path)
+			if c.reset {
+				if err := db.Instance().Where("source = ?", path).Delete(&model.ImportRecord{}).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var got Stats
+			imported, duplicate, err := im.ImportPath(path)
+			switch {
+			case err != nil:
+				t.Fatalf("ImportPath: %v", err)
+			case duplicate:
+				got.Duplicates++
+			case imported:
+				got.Imported++
+			default:
+				got.Skipped++
+			}
+			if got != c.want {
+				t.Errorf("stats = %+v, want %+v", got, c.want)
+			}
+		})
+	}
+
+	var record model.ImportRecord
+	if err := db.Instance().Where("source = ?", filepath.Join(src, "IMG_1.jpg")).First(&record).Error; err != nil {
+		t.Fatal(err)
+	}
+	var f model.File
+	if err := db.Instance().First(&f, record.FileID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if f.Metadata["description"] != "Harbour at dusk" {
+		t.Errorf("description = %q, want the first sidecar's", f.Metadata["description"])
+	}
+}
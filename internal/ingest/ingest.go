@@ -0,0 +1,127 @@
+package ingest
+
+import (
+	"ThinkBank-backend/internal/db"
+	"ThinkBank-backend/internal/model"
+	"ThinkBank-backend/internal/queue"
+	"ThinkBank-backend/internal/service"
+	"ThinkBank-backend/internal/trip"
+	"ThinkBank-backend/internal/util"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/restayway/gogis"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Metadata 导入时附带的元数据，例如 Google Takeout 的 JSON sidecar，优先于 EXIF
+type Metadata struct {
+	TakenAt     *time.Time
+	Latitude    *float64
+	Longitude   *float64
+	Description string
+}
+
+// File 保存原文件并创建记录，随后进入规范化队列。用于上传，队列满时不等待，
+// 由 ScheduleStalledFiles 接手。内容与已有文件相同时不重复保存，返回已有记录并将 duplicate 置为 true
+func File(fileName string, data []byte, fileService service.FileService, meta *Metadata) (record *model.File, duplicate bool, err error) {
+	record, duplicate, err = store(fileName, data, fileService, meta)
+	if err != nil || duplicate {
+		return record, duplicate, err
+	}
+	queue.ProduceNormalizeFile(record.ID, record.OriginalFilePath)
+	return record, false, nil
+}
+
+// store 保存原文件并创建记录，不进入队列。hash 上的唯一索引保证并发写入相同内容时只保留一条
+func store(fileName string, data []byte, fileService service.FileService, meta *Metadata) (record *model.File, duplicate bool, err error) {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	var existing model.File
+	err = db.Instance().Where("hash = ?", hash).First(&existing).Error
+	if err == nil {
+		return &existing, true, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, err
+	}
+
+	// 先在数据库创建记录
+	fileRecord := &model.File{
+		FileName: fileName,
+		Type:     util.GetFileType(fileName),
+		Status:   model.FileStatusPending,
+		Hash:     hash,
+	}
+	if meta != nil {
+		fileRecord.TakenAt = meta.TakenAt
+		if meta.Description != "" {
+			fileRecord.Metadata = map[string]string{"description": meta.Description}
+		}
+	}
+	res := db.Instance().Clauses(clause.OnConflict{
+		Columns:     []clause.Column{{Name: "hash"}},
+		TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "deleted_at IS NULL AND hash IS NOT NULL"}}},
+		DoNothing:   true,
+	}).Create(fileRecord)
+	if res.Error != nil {
+		return nil, false, res.Error
+	}
+	if res.RowsAffected == 0 {
+		// 其他请求刚写入了相同内容
+		if err := db.Instance().Where("hash = ?", hash).First(&existing).Error; err != nil {
+			return nil, false, err
+		}
+		return &existing, true, nil
+	}
+
+	// 构造存储路径
+	subPath := time.Now().Format("2006/01/02")
+	storedFileName := fmt.Sprintf("%d%s", fileRecord.ID, util.GetFileExt(fileName))
+
+	// 保存文件
+	savedPath, err := fileService.Put(storedFileName, data, subPath)
+	if err != nil {
+		db.Instance().Delete(fileRecord)
+		return nil, false, err
+	}
+
+	// 更新数据库路径
+	fileRecord.OriginalFilePath = savedPath
+	if err := db.Instance().Where("id = ?", fileRecord.ID).Updates(fileRecord).Error; err != nil {
+		return nil, false, err
+	}
+
+	if meta != nil && meta.Latitude != nil && meta.Longitude != nil {
+		if err := saveGeo(fileRecord, *meta.Latitude, *meta.Longitude); err != nil {
+			return nil, false, err
+		}
+	}
+
+	return fileRecord, false, nil
+}
+
+// saveGeo 写入导入元数据中的位置，规范化时不会被 EXIF 覆盖。拍摄时间未知时使用上传时间
+func saveGeo(f *model.File, lat, lng float64) error {
+	createAt := f.CreatedAt
+	if f.TakenAt != nil {
+		createAt = *f.TakenAt
+	}
+	record := &model.Geo{
+		ID:        f.ID,
+		Latitude:  lat,
+		Longitude: lng,
+		Geom:      gogis.Point{Lat: lat, Lng: lng},
+		CreateAt:  createAt,
+	}
+	if err := db.Instance().Clauses(clause.OnConflict{UpdateAll: true}).Create(record).Error; err != nil {
+		return err
+	}
+	trip.Notify()
+	return nil
+}
@@ -0,0 +1,105 @@
+package ingest
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// takeoutSidecar Google Takeout 导出的 JSON 元数据
+type takeoutSidecar struct {
+	Title          string `json:"title"`
+	Description    string `json:"description"`
+	PhotoTakenTime struct {
+		Timestamp string `json:"timestamp"`
+	} `json:"photoTakenTime"`
+	GeoData     takeoutGeo `json:"geoData"`
+	GeoDataExif takeoutGeo `json:"geoDataExif"`
+}
+
+type takeoutGeo struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// valid Takeout 用 0, 0 表示没有位置
+func (g takeoutGeo) valid() bool {
+	return g.Latitude != 0 || g.Longitude != 0
+}
+
+// isSidecar 判断是否为 sidecar 文件而非媒体文件
+func isSidecar(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".json")
+}
+
+// sidecarCandidates Takeout 的 sidecar 命名并不统一：
+// IMG_1.jpg.json、IMG_1.jpg.supplemental-metadata.json、文件名过长时被截断，
+// 以及重名文件 IMG_1(1).jpg 对应 IMG_1.jpg(1).json
+func sidecarCandidates(path string) []string {
+	dir, name := filepath.Split(path)
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+
+	candidates := []string{
+		name + ".json",
+		name + ".supplemental-metadata.json",
+		base + ".json",
+	}
+	if i := strings.LastIndex(base, "("); i > 0 && strings.HasSuffix(base, ")") {
+		candidates = append(candidates, base[:i]+ext+base[i:]+".json")
+	}
+	// Takeout 把 sidecar 文件名（不含 .json）截断到 46 个字符
+	if len(name) > 46 {
+		candidates = append(candidates, name[:46]+".json")
+	}
+
+	paths := make([]string, len(candidates))
+	for i, c := range candidates {
+		paths[i] = filepath.Join(dir, c)
+	}
+	return paths
+}
+
+// readSidecar 查找并解析媒体文件对应的 sidecar，不存在时返回 nil
+func readSidecar(path string) (*Metadata, error) {
+	for _, candidate := range sidecarCandidates(path) {
+		data, err := os.ReadFile(candidate)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var sidecar takeoutSidecar
+		if err := json.Unmarshal(data, &sidecar); err != nil {
+			return nil, err
+		}
+		return sidecar.metadata(), nil
+	}
+	return nil, nil
+}
+
+func (s takeoutSidecar) metadata() *Metadata {
+	meta := &Metadata{Description: strings.TrimSpace(s.Description)}
+
+	if ts, err := strconv.ParseInt(s.PhotoTakenTime.Timestamp, 10, 64); err == nil && ts > 0 {
+		t := time.Unix(ts, 0).UTC()
+		meta.TakenAt = &t
+	}
+
+	// geoData 包含用户在相册中手动修改的位置，优先于 geoDataExif
+	geo := s.GeoData
+	if !geo.valid() {
+		geo = s.GeoDataExif
+	}
+	if geo.valid() {
+		lat, lng := geo.Latitude, geo.Longitude
+		meta.Latitude = &lat
+		meta.Longitude = &lng
+	}
+	return meta
+}
@@ -0,0 +1,140 @@
+package ingest
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSidecarCandidates(t *testing.T) {
+	long := strings.Repeat("a", 50) + ".jpg"
+	cases := []struct {
+		name string
+		path string
+		want []string
+	}{
+		{
+			name: "plain",
+			path: "album/IMG_1.jpg",
+			want: []string{"album/IMG_1.jpg.json", "album/IMG_1.jpg.supplemental-metadata.json", "album/IMG_1.json"},
+		},
+		{
+			name: "duplicate name",
+			path: "album/IMG_1(1).jpg",
+			want: []string{
+				"album/IMG_1(1).jpg.json", "album/IMG_1(1).jpg.supplemental-metadata.json", "album/IMG_1(1).json",
+				"album/IMG_1.jpg(1).json",
+			},
+		},
+		{
+			name: "parenthesis at start is not a counter",
+			path: "(1).jpg",
+			want: []string{"(1).jpg.json", "(1).jpg.supplemental-metadata.json", "(1).json"},
+		},
+		{
+			name: "truncated",
+			path: long,
+			want: []string{long + ".json", long + ".supplemental-metadata.json", strings.Repeat("a", 50) + ".json", long[:46] + ".json"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			want := make([]string, len(c.want))
+			for i, w := range c.want {
+				want[i] = filepath.FromSlash(w)
+			}
+			if got := sidecarCandidates(filepath.FromSlash(c.path)); !slices.Equal(got, want) {
+				t.Errorf("sidecarCandidates(%q) = %q, want %q", c.path, got, want)
+			}
+		})
+	}
+}
+
+func TestSidecarMetadata(t *testing.T) {
+	taken := time.Unix(1689330030, 0).UTC()
+	cases := []struct {
+		name      string
+		json      string
+		wantTaken *time.Time
+		wantLoc   *[2]float64
+		wantDesc  string
+	}{
+		{
+			name:      "full",
+			json:      `{"description":" Beach ","photoTakenTime":{"timestamp":"1689330030"},"geoData":{"latitude":-33.86,"longitude":151.21}}`,
+			wantTaken: &taken,
+			wantLoc:   &[2]float64{-33.86, 151.21},
+			wantDesc:  "Beach",
+		},
+		{
+			name:    "geoData preferred over geoDataExif",
+			json:    `{"geoData":{"latitude":1.5,"longitude":2.5},"geoDataExif":{"latitude":3.5,"longitude":4.5}}`,
+			wantLoc: &[2]float64{1.5, 2.5},
+		},
+		{
+			name:    "geoDataExif when geoData is empty",
+			json:    `{"geoData":{"latitude":0,"longitude":0},"geoDataExif":{"latitude":3.5,"longitude":4.5}}`,
+			wantLoc: &[2]float64{3.5, 4.5},
+		},
+		{
+			name: "zero location ignored",
+			json: `{"geoData":{"latitude":0,"longitude":0},"geoDataExif":{"latitude":0,"longitude":0}}`,
+		},
+		{
+			name: "invalid timestamp ignored",
+			json: `{"photoTakenTime":{"timestamp":"not a number"}}`,
+		},
+		{
+			name: "zero timestamp ignored",
+			json: `{"photoTakenTime":{"timestamp":"0"}}`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var s takeoutSidecar
+			if err := json.Unmarshal([]byte(c.json), &s); err != nil {
+				t.Fatal(err)
+			}
+			meta := s.metadata()
+
+			if c.wantTaken == nil && meta.TakenAt != nil {
+				t.Errorf("TakenAt = %v, want nil", *meta.TakenAt)
+			} else if c.wantTaken != nil && (meta.TakenAt == nil || !meta.TakenAt.Equal(*c.wantTaken)) {
+				t.Errorf("TakenAt = %v, want %v", meta.TakenAt, *c.wantTaken)
+			}
+			if c.wantLoc == nil && meta.Latitude != nil {
+				t.Errorf("location = (%v, %v), want none", *meta.Latitude, *meta.Longitude)
+			} else if c.wantLoc != nil && (meta.Latitude == nil || meta.Longitude == nil ||
+				*meta.Latitude != c.wantLoc[0] || *meta.Longitude != c.wantLoc[1]) {
+				t.Errorf("location = (%v, %v), want %v", meta.Latitude, meta.Longitude, *c.wantLoc)
+			}
+			if meta.Description != c.wantDesc {
+				t.Errorf("Description = %q, want %q", meta.Description, c.wantDesc)
+			}
+		})
+	}
+}
+
+func TestReadSidecar(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "IMG_1.jpg(1).json"), []byte(`{"description":"copy"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	meta, err := readSidecar(filepath.Join(dir, "IMG_1(1).jpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta == nil || meta.Description != "copy" {
+		t.Errorf("readSidecar = %+v, want description copy", meta)
+	}
+
+	meta, err = readSidecar(filepath.Join(dir, "IMG_2.jpg"))
+	if err != nil || meta != nil {
+		t.Errorf("readSidecar without sidecar = %+v, %v, want nil, nil", meta, err)
+	}
+}
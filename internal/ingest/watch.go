@@ -0,0 +1,148 @@
+package ingest
+
+import (
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Watcher 监听目录并自动导入新增或修改的文件。优先使用 inotify，不可用时退化为轮询。
+// 文件在 Debounce 时间内大小与修改时间不再变化才会导入，避免读到写了一半的文件
+type Watcher struct {
+	Dirs         []string
+	Importer     *Importer
+	Debounce     time.Duration
+	PollInterval time.Duration
+
+	mu      sync.Mutex
+	pending map[string]*pendingFile
+	seen    map[string]fileState // 轮询模式下上一次扫描到的状态
+}
+
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+type pendingFile struct {
+	state   fileState
+	changed time.Time
+}
+
+// Start 扫描一遍现有文件并开始监听，非阻塞
+func (w *Watcher) Start() {
+	w.pending = make(map[string]*pendingFile)
+	w.seen = make(map[string]fileState)
+
+	// 停机期间的变化由首次扫描补上，已导入的文件会在 ImportPath 中跳过
+	for _, dir := range w.Dirs {
+		w.scan(dir)
+	}
+
+	if err := w.watchNotify(); err != nil {
+		log.Printf("inotify unavailable (%v), polling %v every %s", err, w.Dirs, w.PollInterval)
+		go w.poll()
+	} else {
+		log.Printf("Watching %v with inotify", w.Dirs)
+	}
+
+	go w.flush()
+}
+
+// scan 遍历目录，把状态有变化的文件标记为待导入
+func (w *Watcher) scan(root string) {
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			log.Printf("Watch scan error at %s: %v", path, err)
+			return nil
+		}
+		if d.IsDir() || isSidecar(path) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+
+		state := fileState{size: info.Size(), modTime: info.ModTime()}
+		w.mu.Lock()
+		if prev, ok := w.seen[path]; !ok || prev != state {
+			w.seen[path] = state
+			w.markLocked(path, state)
+		}
+		w.mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		log.Printf("Watch scan of %s failed: %v", root, err)
+	}
+}
+
+func (w *Watcher) poll() {
+	ticker := time.NewTicker(w.PollInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		for _, dir := range w.Dirs {
+			w.scan(dir)
+		}
+	}
+}
+
+// mark 记录文件变化，重新开始计算防抖时间
+func (w *Watcher) mark(path string) {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() || isSidecar(path) {
+		return
+	}
+	w.mu.Lock()
+	w.markLocked(path, fileState{size: info.Size(), modTime: info.ModTime()})
+	w.mu.Unlock()
+}
+
+func (w *Watcher) markLocked(path string, state fileState) {
+	w.pending[path] = &pendingFile{state: state, changed: time.Now()}
+}
+
+// flush 定期导入已稳定的文件
+func (w *Watcher) flush() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		for _, path := range w.stable() {
+			if _, _, err := w.Importer.ImportPath(path); err != nil {
+				log.Printf("Failed to import watched file %s: %v", path, err)
+			}
+		}
+	}
+}
+
+// stable 取出防抖时间内没有变化的文件；期间仍在写入的文件重新计时
+func (w *Watcher) stable() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var ready []string
+	now := time.Now()
+	for path, p := range w.pending {
+		if now.Sub(p.changed) < w.Debounce {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			// 临时文件被重命名或删除
+			delete(w.pending, path)
+			continue
+		}
+		state := fileState{size: info.Size(), modTime: info.ModTime()}
+		if state != p.state {
+			w.markLocked(path, state)
+			continue
+		}
+		delete(w.pending, path)
+		ready = append(ready, path)
+	}
+	return ready
+}
@@ -0,0 +1,108 @@
+//go:build linux
+
+package ingest
+
+import (
+	"io/fs"
+	"log"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const watchMask = unix.IN_CLOSE_WRITE | unix.IN_MOVED_TO | unix.IN_CREATE | unix.IN_MODIFY
+
+// inotify 监听句柄，wd 与目录一一对应
+type inotify struct {
+	fd   int
+	mu   sync.Mutex
+	dirs map[int]string
+}
+
+// watchNotify 为所有目录（含子目录）注册 inotify 并在后台读取事件
+func (w *Watcher) watchNotify() error {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC)
+	if err != nil {
+		return err
+	}
+	in := &inotify{fd: fd, dirs: make(map[int]string)}
+
+	for _, dir := range w.Dirs {
+		if err := in.addRecursive(dir); err != nil {
+			_ = unix.Close(fd)
+			return err
+		}
+	}
+
+	go w.readEvents(in)
+	return nil
+}
+
+func (in *inotify) addRecursive(root string) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return err
+		}
+		wd, err := unix.InotifyAddWatch(in.fd, path, watchMask)
+		if err != nil {
+			return err
+		}
+		in.mu.Lock()
+		in.dirs[wd] = path
+		in.mu.Unlock()
+		return nil
+	})
+}
+
+func (w *Watcher) readEvents(in *inotify) {
+	buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+	for {
+		n, err := unix.Read(in.fd, buf)
+		if err != nil {
+			if err == unix.EINTR {
+				continue
+			}
+			log.Printf("inotify read failed (%v), falling back to polling", err)
+			go w.poll()
+			return
+		}
+
+		for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+			event := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameBytes := buf[offset+unix.SizeofInotifyEvent : offset+unix.SizeofInotifyEvent+int(event.Len)]
+			name := strings.TrimRight(string(nameBytes), "\x00")
+			offset += unix.SizeofInotifyEvent + int(event.Len)
+
+			if event.Mask&unix.IN_Q_OVERFLOW != 0 {
+				// 事件丢失，整体重新扫描
+				for _, dir := range w.Dirs {
+					w.scan(dir)
+				}
+				continue
+			}
+
+			in.mu.Lock()
+			dir, ok := in.dirs[int(event.Wd)]
+			in.mu.Unlock()
+			if !ok || name == "" {
+				continue
+			}
+			path := filepath.Join(dir, name)
+
+			if event.Mask&unix.IN_ISDIR != 0 {
+				// 新建或移入的子目录：注册监听并补扫已有文件
+				if event.Mask&(unix.IN_CREATE|unix.IN_MOVED_TO) != 0 {
+					if err := in.addRecursive(path); err != nil {
+						log.Printf("Failed to watch %s: %v", path, err)
+					}
+					w.scan(path)
+				}
+				continue
+			}
+			w.mark(path)
+		}
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package ingest
+
+import "errors"
+
+// watchNotify 非 Linux 平台没有 inotify，使用轮询
+func (w *Watcher) watchNotify() error {
+	return errors.New("inotify is only supported on linux")
+}
@@ -0,0 +1,19 @@
+package model
+
+import (
+	"time"
+
+	"github.com/pgvector/pgvector-go"
+)
+
+// Embedding 文件在某个模型下的向量，同一文件可同时保存多个模型的结果
+type Embedding struct {
+	ID           uint            `gorm:"primaryKey"`
+	FileID       uint            `gorm:"not null;uniqueIndex:idx_embeddings_file_model"`
+	ModelName    string          `gorm:"type:text;not null;uniqueIndex:idx_embeddings_file_model;index:idx_embeddings_model"`
+	ModelVersion string          `gorm:"type:text;not null;uniqueIndex:idx_embeddings_file_model;index:idx_embeddings_model"`
+	Dimension    int             `gorm:"not null"`
+	Vector       pgvector.Vector `gorm:"type:vector;not null"` // 不限定维度，按模型建表达式索引
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
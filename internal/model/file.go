@@ -1,19 +1,41 @@
 package model
 
 import (
-	"github.com/pgvector/pgvector-go"
+	"time"
+
 	"gorm.io/gorm"
 )
 
+// 文件处理状态
+const (
+	FileStatusPending    = "pending"    // 已上传，等待规范化
+	FileStatusNormalized = "normalized" // 已规范化，等待生成向量
+	FileStatusReady      = "ready"      // 处理完成
+	FileStatusFailed     = "failed"     // 规范化或生成向量失败
+)
+
 type File struct {
 	gorm.Model
-	FileName         string            `gorm:"type:text"`        // 原文件名
-	OriginalFilePath string            `gorm:"type:text"`        // 文件存储路径
-	FilePath         string            `gorm:"type:text"`        // 文件存储路径
-	Metadata         map[string]string `gorm:"type:jsonb"`       // 文件元数据，如作者、定位等
-	Type             string            `gorm:"type:text"`        // image / document
-	Caption          string            `gorm:"type:text"`        // 模型生成描述
-	Tags             []string          `gorm:"type:jsonb"`       // 关键词列表
-	Vector           *pgvector.Vector  `gorm:"type:vector(512)"` // embedding 向量
-	TSV              string            `gorm:"-"`                // 用于倒排索引
+	FileName         string            `gorm:"type:text"`                         // 原文件名
+	OriginalFilePath string            `gorm:"type:text"`                         // 文件存储路径
+	FilePath         string            `gorm:"type:text"`                         // 文件存储路径
+	Metadata         map[string]string `gorm:"type:jsonb;serializer:json"`        // 文件元数据，如作者、定位等
+	Type             string            `gorm:"type:text"`                         // image / document
+	Caption          string            `gorm:"type:text"`                         // 模型生成描述
+	Tags             []string          `gorm:"type:jsonb;serializer:json"`        // 关键词列表
+	Status           string            `gorm:"type:text;default:'pending';index"` // 处理状态
+	Hash             string            `gorm:"type:text;index"`                   // 原文件 SHA-256，用于去重
+	TakenAt          *time.Time        `gorm:"index"`                             // 拍摄时间，来自 EXIF 或导入时的元数据
+	TSV              string            `gorm:"-"`                                 // 用于倒排索引
+}
+
+// ThumbnailURL 返回用于预览的图片地址，优先使用规范化后的 JPEG
+func (f *File) ThumbnailURL() string {
+	if f.Type != "image" {
+		return ""
+	}
+	if f.FilePath != "" {
+		return f.FilePath
+	}
+	return f.OriginalFilePath
 }
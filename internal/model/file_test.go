@@ -0,0 +1,71 @@
+package model_test
+
+import (
+	"ThinkBank-backend/internal/db"
+	"ThinkBank-backend/internal/db/migrate"
+	"ThinkBank-backend/internal/model"
+	"maps"
+	"os"
+	"slices"
+	"testing"
+)
+
+// openTestDB 连接 THINKBANK_TEST_DSN 指定的数据库（需安装 postgis 与 vector）并执行迁移，未设置时跳过
+func openTestDB(t *testing.T) {
+	t.Helper()
+	dsn := os.Getenv("THINKBANK_TEST_DSN")
+	if dsn == "" {
+		t.Skip("THINKBANK_TEST_DSN not set")
+	}
+	if err := db.Open(dsn); err != nil {
+		t.Fatal(err)
+	}
+	if err := migrate.Up(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileJSONColumns(t *testing.T) {
+	openTestDB(t)
+
+	cases := []struct {
+		name     string
+		metadata map[string]string
+		tags     []string
+	}{
+		{"populated", map[string]string{"make": "Canon", "iso": "400"}, []string{"beach", "sunset"}},
+		{"empty", nil, nil},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			f := &model.File{FileName: "roundtrip.jpg", Type: "image", Metadata: c.metadata, Tags: c.tags}
+			if err := db.Instance().Create(f).Error; err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { db.Instance().Unscoped().Delete(&model.File{}, f.ID) })
+
+			// 与规范化时写入元数据的方式一致
+			if err := db.Instance().Exec(
+				`UPDATE files SET metadata = '{"city":"Sydney"}'::jsonb || COALESCE(metadata, '{}'::jsonb) WHERE id = ?`, f.ID,
+			).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			var files []model.File
+			if err := db.Instance().Where("id = ?", f.ID).Find(&files).Error; err != nil {
+				t.Fatal(err)
+			}
+			if len(files) != 1 {
+				t.Fatalf("found %d files, want 1", len(files))
+			}
+			want := map[string]string{"city": "Sydney"}
+			maps.Copy(want, c.metadata)
+			if !maps.Equal(files[0].Metadata, want) {
+				t.Errorf("Metadata = %v, want %v", files[0].Metadata, want)
+			}
+			if !slices.Equal(files[0].Tags, c.tags) {
+				t.Errorf("Tags = %v, want %v", files[0].Tags, c.tags)
+			}
+		})
+	}
+}
@@ -6,11 +6,15 @@ import (
 	"github.com/restayway/gogis"
 )
 
+// Geo 文件的拍摄位置。Geom、Geom3857 与地名由数据库触发器根据经纬度生成
 type Geo struct {
 	ID        uint        `gorm:"primaryKey;autoIncrement:false;uniqueIndex"`
 	Latitude  float64     `gorm:"not null"`
 	Longitude float64     `gorm:"not null"`
 	Geom      gogis.Point `gorm:"type:geometry(Point,4326);index:idx_geo_geom_gist,type:gist"`
-	Geom3857  gogis.Point `gorm:"type:geometry(Point,3857);index:idx_geo_3857_gist,type:gist"`
+	Geom3857  gogis.Point `gorm:"->;type:geometry(Point,3857);index:idx_geo_3857_gist,type:gist"` // 只读，Point 写入时固定为 SRID 4326
+	Country   *string     `gorm:"->"`                                                             // 逆地理编码的国家/地区/城市，可能为空
+	Region    *string     `gorm:"->"`
+	City      *string     `gorm:"->"`
 	CreateAt  time.Time
 }
@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// ImportRecord 记录已导入的本地文件，重复导入或中断后继续时据此跳过
+type ImportRecord struct {
+	ID        uint      `gorm:"primaryKey"`
+	Source    string    `gorm:"type:text;not null;uniqueIndex"` // 本地绝对路径
+	Size      int64     `gorm:"not null"`
+	ModTime   time.Time `gorm:"not null"`
+	FileID    uint      `gorm:"not null;index"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
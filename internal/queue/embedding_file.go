@@ -4,9 +4,12 @@ import (
 	"ThinkBank-backend/internal/db"
 	"ThinkBank-backend/internal/model"
 	"ThinkBank-backend/internal/service"
+	"ThinkBank-backend/internal/vector"
 	"log"
+	"time"
 
 	"github.com/pgvector/pgvector-go"
+	"gorm.io/gorm/clause"
 )
 
 // ProduceEmbeddingFile 推送消息到 embedding_file 队列
@@ -26,20 +29,57 @@ func ConsumeEmbeddingFile(modelService service.ModelService, n int) {
 			return
 		}
 
-		caption, embedding, err := modelService.AnalyzeImage(payload.Path)
+		err := embedFile(modelService, payload)
 		if err != nil {
-			log.Println("Analyze image error:", err)
-			return
-		}
-
-		// 更新数据库
-		err = db.Instance().Model(&model.File{}).Where("id = ?", payload.ID).Updates(map[string]interface{}{
-			"caption": caption,
-			"vector":  pgvector.NewVector(embedding),
-		}).Error
-		if err != nil {
-			log.Println("Update database error:", err)
-			return
+			setFileStatus(payload.ID, model.FileStatusFailed)
+		} else {
+			setFileStatus(payload.ID, model.FileStatusReady)
 		}
+		jobs.done(payload.JobID, "embedding_file", err)
 	}, n)
 }
+
+func embedFile(modelService service.ModelService, payload Payload) error {
+	caption, embedding, err := modelService.AnalyzeImage(payload.Path)
+	if err != nil {
+		log.Println("Analyze image error:", err)
+		return err
+	}
+
+	// 更新数据库
+	err = db.Instance().Model(&model.File{}).Where("id = ?", payload.ID).Updates(map[string]interface{}{
+		"caption": caption,
+	}).Error
+	if err != nil {
+		log.Println("Update database error:", err)
+		return err
+	}
+
+	if err := SaveEmbedding(payload.ID, vector.ActiveModel(), embedding); err != nil {
+		log.Println("Save embedding error:", err)
+		return err
+	}
+	return nil
+}
+
+// SaveEmbedding 写入或覆盖文件在指定模型下的向量
+func SaveEmbedding(fileID uint, m vector.Model, embedding []float32) error {
+	if err := m.Check(embedding); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	record := &model.Embedding{
+		FileID:       fileID,
+		ModelName:    m.Name,
+		ModelVersion: m.Version,
+		Dimension:    m.Dimension,
+		Vector:       pgvector.NewVector(vector.Prepare(embedding)),
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	return db.Instance().Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "file_id"}, {Name: "model_name"}, {Name: "model_version"}},
+		DoUpdates: clause.AssignmentColumns([]string{"dimension", "vector", "updated_at"}),
+	}).Create(record).Error
+}
@@ -0,0 +1,153 @@
+package queue
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sort"
+	"sync"
+	"time"
+)
+
+// StageProgress 单个队列阶段的进度
+type StageProgress struct {
+	Queued int64 `json:"queued"`
+	Done   int64 `json:"done"`
+	Failed int64 `json:"failed"`
+}
+
+// Job 批处理任务，记录各阶段的入队与完成数量
+type Job struct {
+	ID         string                    `json:"id"`
+	Kind       string                    `json:"kind"`
+	Total      int64                     `json:"total"`
+	Finished   bool                      `json:"finished"` // 是否已全部入队
+	Error      string                    `json:"error,omitempty"`
+	Stages     map[string]*StageProgress `json:"stages"`
+	CreatedAt  time.Time                 `json:"createdAt"`
+	FinishedAt *time.Time                `json:"finishedAt,omitempty"`
+}
+
+// jobRetention 已全部入队的任务保留多久，之后在创建新任务时清理
+const jobRetention = 24 * time.Hour
+
+type jobRegistry struct {
+	mu   sync.Mutex
+	jobs map[string]*Job
+}
+
+var jobs = &jobRegistry{jobs: make(map[string]*Job)}
+
+func (r *jobRegistry) create(kind string, stages ...string) *Job {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+
+	job := &Job{
+		ID:        hex.EncodeToString(b),
+		Kind:      kind,
+		Stages:    make(map[string]*StageProgress),
+		CreatedAt: time.Now(),
+	}
+	for _, s := range stages {
+		job.Stages[s] = &StageProgress{}
+	}
+
+	r.mu.Lock()
+	r.evict(job.CreatedAt.Add(-jobRetention))
+	r.jobs[job.ID] = job
+	r.mu.Unlock()
+	return job
+}
+
+// evict 删除在 before 之前已全部入队的任务，调用方持有锁
+func (r *jobRegistry) evict(before time.Time) {
+	for id, job := range r.jobs {
+		if job.Finished && job.FinishedAt != nil && job.FinishedAt.Before(before) {
+			delete(r.jobs, id)
+		}
+	}
+}
+
+func (r *jobRegistry) update(id string, fn func(*Job)) {
+	if id == "" {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if job, ok := r.jobs[id]; ok {
+		fn(job)
+	}
+}
+
+func (r *jobRegistry) stage(job *Job, topic string) *StageProgress {
+	s, ok := job.Stages[topic]
+	if !ok {
+		s = &StageProgress{}
+		job.Stages[topic] = s
+	}
+	return s
+}
+
+func (r *jobRegistry) queued(id, topic string) {
+	r.update(id, func(job *Job) {
+		r.stage(job, topic).Queued++
+	})
+}
+
+func (r *jobRegistry) done(id, topic string, err error) {
+	r.update(id, func(job *Job) {
+		s := r.stage(job, topic)
+		if err != nil {
+			s.Failed++
+		} else {
+			s.Done++
+		}
+	})
+}
+
+func (r *jobRegistry) finish(id string, total int64, err error) {
+	r.update(id, func(job *Job) {
+		now := time.Now()
+		job.Total = total
+		job.Finished = true
+		job.FinishedAt = &now
+		if err != nil {
+			job.Error = err.Error()
+		}
+	})
+}
+
+// snapshot 返回任务副本，避免调用方读到并发修改中的数据
+func (r *jobRegistry) snapshot(job *Job) Job {
+	cp := *job
+	cp.Stages = make(map[string]*StageProgress, len(job.Stages))
+	for k, v := range job.Stages {
+		s := *v
+		cp.Stages[k] = &s
+	}
+	return cp
+}
+
+// GetJob 查询任务进度
+func GetJob(id string) (Job, bool) {
+	jobs.mu.Lock()
+	defer jobs.mu.Unlock()
+	job, ok := jobs.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return jobs.snapshot(job), true
+}
+
+// ListJobs 按创建时间倒序列出任务
+func ListJobs() []Job {
+	jobs.mu.Lock()
+	defer jobs.mu.Unlock()
+	list := make([]Job, 0, len(jobs.jobs))
+	for _, job := range jobs.jobs {
+		list = append(list, jobs.snapshot(job))
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.After(list[j].CreatedAt)
+	})
+	return list
+}
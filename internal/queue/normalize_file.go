@@ -4,7 +4,9 @@ import (
 	"ThinkBank-backend/internal/db"
 	"ThinkBank-backend/internal/model"
 	"ThinkBank-backend/internal/service"
+	"ThinkBank-backend/internal/trip"
 	"ThinkBank-backend/internal/util"
+	"encoding/json"
 	"fmt"
 	_ "image/gif"
 	_ "image/jpeg"
@@ -15,6 +17,7 @@ import (
 	"time"
 
 	"github.com/restayway/gogis"
+	"gorm.io/gorm/clause"
 )
 
 func ProduceNormalizeFile(id uint, filePath string) {
@@ -25,6 +28,11 @@ func ProduceNormalizeFile(id uint, filePath string) {
 	GlobalQueue.Produce("normalize_file", payload)
 }
 
+// ProduceNormalizeFileWait 队列满时阻塞等待，用于批量导入
+func ProduceNormalizeFileWait(id uint, filePath string) {
+	GlobalQueue.ProduceWait("normalize_file", Payload{ID: id, Path: filePath})
+}
+
 func ConsumeNormalizeFile(concurrency int, fromFS, toFS service.FileService) {
 	GlobalQueue.RegisterConsumer("normalize_file", func(msg Message) {
 		handleNormalizeFile(msg, fromFS, toFS)
@@ -41,23 +49,42 @@ func handleNormalizeFile(msg Message, fromFS, toFS service.FileService) {
 	id := payload.ID
 	originalPath := payload.Path
 
-	normalizedPath := processFile(fromFS, toFS, originalPath, id)
+	normalizedPath, err := processFile(fromFS, toFS, originalPath, id)
+	if err != nil {
+		setFileStatus(id, model.FileStatusFailed)
+		jobs.done(payload.JobID, "normalize_file", err)
+		return
+	}
 
-	err := db.Instance().Model(&model.File{}).
+	err = db.Instance().Model(&model.File{}).
 		Where("id = ?", id).
-		Update("file_path", normalizedPath).Error
+		Updates(map[string]interface{}{
+			"file_path": normalizedPath,
+			"status":    model.FileStatusNormalized,
+		}).Error
 	if err != nil {
 		fmt.Println("Update file error:", err)
 	}
+	jobs.done(payload.JobID, "normalize_file", err)
+
+	// 阻塞等待而不是丢弃，否则任务已计入的消息永远不会完成
+	jobs.queued(payload.JobID, "embedding_file")
+	GlobalQueue.ProduceWait("embedding_file", Payload{ID: id, Path: normalizedPath, JobID: payload.JobID})
+}
 
-	ProduceEmbeddingFile(id, normalizedPath)
+// setFileStatus 更新文件处理状态
+func setFileStatus(id uint, status string) {
+	err := db.Instance().Model(&model.File{}).Where("id = ?", id).Update("status", status).Error
+	if err != nil {
+		log.Println("Update file status error:", err)
+	}
 }
 
-func processFile(fromFS, toFS service.FileService, path string, id uint) string {
+func processFile(fromFS, toFS service.FileService, path string, id uint) (string, error) {
 	resp, err := http.Get(path)
 	if err != nil {
 		log.Println("Failed to normalize file because of http.Get error:", err)
-		return path
+		return "", err
 	}
 	defer func(Body io.ReadCloser) {
 		err := Body.Close()
@@ -69,20 +96,20 @@ func processFile(fromFS, toFS service.FileService, path string, id uint) string
 	data, err := io.ReadAll(resp.Body)
 	if err != nil {
 		log.Println("Failed to normalize file because of ReadAll error:", err)
-		return path
+		return "", err
 	}
 
 	ext := util.GetFileExt(path)
 	var newData []byte
 	var newExt string
-	var exifInfo *util.ExifInfo
+	var meta *util.ImageMetadata
 
 	switch ext {
 	case ".jpg", ".jpeg", ".png", ".webp", ".gif", ".heic", ".livp", ".apng":
-		newData, exifInfo, err = util.ProcessImageToJPEG(data, ext)
+		newData, meta, err = util.ProcessImageToJPEG(data, ext)
 		if err != nil {
 			log.Println("Image process error:", err)
-			return path
+			return "", err
 		}
 		newExt = ".jpg"
 
@@ -100,21 +127,65 @@ func processFile(fromFS, toFS service.FileService, path string, id uint) string
 	toPath, err := toFS.Put(storedFileName, newData, newSubPath)
 	if err != nil {
 		log.Println("Put file to filesystem error:", err)
-		return path
+		return "", err
+	}
+
+	if meta != nil {
+		saveImageMetadata(id, meta)
+	}
+
+	return toPath, nil
+}
+
+// saveImageMetadata 写入提取出的元数据。导入时 sidecar 提供的字段、拍摄时间与位置优先，不会被覆盖
+func saveImageMetadata(id uint, meta *util.ImageMetadata) {
+	if len(meta.Fields) > 0 {
+		fields, err := json.Marshal(meta.Fields)
+		if err != nil {
+			log.Println("Error while encoding metadata:", err)
+		} else {
+			err = db.Instance().Exec(
+				"UPDATE files SET metadata = ?::jsonb || COALESCE(metadata, '{}'::jsonb) WHERE id = ?",
+				string(fields), id,
+			).Error
+			if err != nil {
+				log.Println("Error while recording metadata:", err)
+			}
+		}
+	}
+
+	if meta.TakenAt != nil {
+		err := db.Instance().Model(&model.File{}).
+			Where("id = ? AND taken_at IS NULL", id).
+			Update("taken_at", *meta.TakenAt).Error
+		if err != nil {
+			log.Println("Error while recording taken time:", err)
+		}
 	}
 
-	if exifInfo != nil {
+	if meta.HasLocation() {
+		// 位置时间与文件的拍摄时间一致，拍摄时间未知时使用上传时间
+		var f model.File
+		if err := db.Instance().Select("id", "created_at", "taken_at").First(&f, id).Error; err != nil {
+			log.Println("Error while loading file for EXIF info:", err)
+			return
+		}
+		createAt := f.CreatedAt
+		if f.TakenAt != nil {
+			createAt = *f.TakenAt
+		}
 		record := &model.Geo{
 			ID:        id,
-			Latitude:  exifInfo.Latitude,
-			Longitude: exifInfo.Longitude,
-			Geom:      gogis.Point{Lat: exifInfo.Latitude, Lng: exifInfo.Longitude},
-			CreateAt:  exifInfo.CreateAt,
+			Latitude:  *meta.Latitude,
+			Longitude: *meta.Longitude,
+			Geom:      gogis.Point{Lat: *meta.Latitude, Lng: *meta.Longitude},
+			CreateAt:  createAt,
 		}
-		if err := db.Instance().Create(record).Error; err != nil {
+		// 导入时 sidecar 写入的位置优先
+		if err := db.Instance().Clauses(clause.OnConflict{DoNothing: true}).Create(record).Error; err != nil {
 			log.Println("Error while recording EXIF info:", err)
+		} else {
+			trip.Notify()
 		}
 	}
-
-	return toPath
 }
@@ -6,8 +6,9 @@ import (
 )
 
 type Payload struct {
-	ID   uint
-	Path string
+	ID    uint
+	Path  string
+	JobID string // 所属批处理任务，普通上传为空
 }
 
 // Message 定义消息结构
@@ -52,6 +53,11 @@ func (q *Queue) CheckTopic(topic string) chan Message {
 	return ch
 }
 
+// Pending 返回 topic 中尚未被消费的消息数
+func (q *Queue) Pending(topic string) int {
+	return len(q.CheckTopic(topic))
+}
+
 // Produce 生产消息
 func (q *Queue) Produce(topic string, data any) {
 	ch := q.CheckTopic(topic)
@@ -64,6 +70,11 @@ func (q *Queue) Produce(topic string, data any) {
 	}
 }
 
+// ProduceWait 生产消息，队列满时阻塞等待，用于批量任务
+func (q *Queue) ProduceWait(topic string, data any) {
+	q.CheckTopic(topic) <- Message{Topic: topic, Data: data}
+}
+
 // RegisterConsumer 注册消费者，支持 n 个并发消费者
 func (q *Queue) RegisterConsumer(topic string, handler func(Message), n int) {
 	ch := q.CheckTopic(topic)
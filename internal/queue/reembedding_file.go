@@ -0,0 +1,98 @@
+package queue
+
+import (
+	"ThinkBank-backend/internal/db"
+	"ThinkBank-backend/internal/service"
+	"ThinkBank-backend/internal/vector"
+	"log"
+	"time"
+)
+
+const (
+	maxReembeddingAttempts = 5                // 失败达到次数后不再重试
+	reembeddingRetryDelay  = 10 * time.Minute // 失败后至少间隔多久再重试
+)
+
+// ScheduleReembedding 周期性地把已有当前模型向量、但缺少目标模型向量的文件推入 reembedding_file 队列。
+// 迁移期间搜索仍使用当前模型，全部完成后切换 EMBEDDING_MODEL 即可。
+// 多次失败的文件记录在 reembedding_failures 中并被跳过
+func ScheduleReembedding(target vector.Model, batch int, interval time.Duration) {
+	service.RegisterPeriodicService(func() {
+		// 上一批尚未处理完时不重复推送
+		if GlobalQueue.Pending("reembedding_file") > 0 {
+			return
+		}
+
+		var files []Payload
+		err := db.Instance().Raw(`
+            SELECT f.id, f.file_path AS path
+            FROM files f
+            WHERE f.deleted_at IS NULL
+              AND f.file_path <> ''
+              AND EXISTS (
+                SELECT 1 FROM embeddings e
+                WHERE e.file_id = f.id AND `+vector.ActiveModel().Predicate()+`
+              )
+              AND NOT EXISTS (
+                SELECT 1 FROM embeddings e
+                WHERE e.file_id = f.id AND `+target.Predicate()+`
+              )
+              AND NOT EXISTS (
+                SELECT 1 FROM reembedding_failures x
+                WHERE x.file_id = f.id AND `+target.Predicate()+`
+                  AND (x.attempts >= ? OR x.failed_at > ?)
+              )
+            ORDER BY f.id
+            LIMIT ?
+        `, maxReembeddingAttempts, time.Now().Add(-reembeddingRetryDelay), batch).Scan(&files).Error
+		if err != nil {
+			log.Println("Failed to scan files for re-embedding:", err)
+			return
+		}
+
+		for _, f := range files {
+			GlobalQueue.Produce("reembedding_file", f)
+		}
+		if len(files) > 0 {
+			log.Printf("Re-embedding %d files with %s", len(files), target.Key())
+		}
+	}, interval)
+}
+
+// ConsumeReembeddingFile 启动 n 个并发消费者，使用目标模型服务生成向量
+func ConsumeReembeddingFile(modelService service.ModelService, target vector.Model, n int) {
+	GlobalQueue.RegisterConsumer("reembedding_file", func(msg Message) {
+		payload, ok := msg.Data.(Payload)
+		if !ok {
+			log.Println("Invalid payload for re-embedding file, skipping")
+			return
+		}
+
+		_, embedding, err := modelService.AnalyzeImage(payload.Path)
+		if err != nil {
+			log.Println("Analyze image error:", err)
+			recordReembeddingFailure(payload.ID, target, err)
+			return
+		}
+
+		if err := SaveEmbedding(payload.ID, target, embedding); err != nil {
+			log.Println("Save embedding error:", err)
+			recordReembeddingFailure(payload.ID, target, err)
+		}
+	}, n)
+}
+
+// recordReembeddingFailure 累加文件在目标模型下的失败次数
+func recordReembeddingFailure(fileID uint, target vector.Model, cause error) {
+	err := db.Instance().Exec(`
+        INSERT INTO reembedding_failures (file_id, model_name, model_version, attempts, last_error, failed_at)
+        VALUES (?, ?, ?, 1, ?, now())
+        ON CONFLICT (file_id, model_name, model_version) DO UPDATE
+        SET attempts = reembedding_failures.attempts + 1,
+            last_error = EXCLUDED.last_error,
+            failed_at = EXCLUDED.failed_at
+    `, fileID, target.Name, target.Version, cause.Error()).Error
+	if err != nil {
+		log.Println("Failed to record re-embedding failure:", err)
+	}
+}
@@ -0,0 +1,127 @@
+package queue
+
+import (
+	"ThinkBank-backend/internal/db"
+	"ThinkBank-backend/internal/model"
+	"ThinkBank-backend/internal/vector"
+	"errors"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const reindexBatchSize = 500
+
+// ReindexFilter 选择需要重新处理的文件，各条件之间为 AND
+type ReindexFilter struct {
+	Type           string     `json:"type"`           // image / document
+	From           *time.Time `json:"from"`           // 上传时间下界（含）
+	To             *time.Time `json:"to"`             // 上传时间上界（不含）
+	MissingCaption bool       `json:"missingCaption"` // 没有模型描述
+	MissingVector  bool       `json:"missingVector"`  // 没有当前模型的向量
+	Failed         bool       `json:"failed"`         // 上次处理失败
+}
+
+// ReindexRequest 重新处理请求。Normalize 会在规范化完成后自动生成向量，
+// 只指定 Embedding 时直接使用已规范化的文件
+type ReindexRequest struct {
+	ReindexFilter
+	Normalize bool `json:"normalize"`
+	Embedding bool `json:"embedding"`
+}
+
+// Apply 把筛选条件加到 files 查询上
+func (f ReindexFilter) Apply(q *gorm.DB) *gorm.DB {
+	if f.Type != "" {
+		q = q.Where("type = ?", f.Type)
+	}
+	if f.From != nil {
+		q = q.Where("created_at >= ?", *f.From)
+	}
+	if f.To != nil {
+		q = q.Where("created_at < ?", *f.To)
+	}
+	if f.MissingCaption {
+		q = q.Where("(caption IS NULL OR caption = '')")
+	}
+	if f.MissingVector {
+		q = q.Where(`NOT EXISTS (
+            SELECT 1 FROM embeddings e
+            WHERE e.file_id = files.id AND ` + vector.ActiveModel().Predicate() + `)`)
+	}
+	if f.Failed {
+		q = q.Where("status = ?", model.FileStatusFailed)
+	}
+	return q
+}
+
+// StartReindex 创建任务并在后台按批次把匹配的文件推入队列
+func StartReindex(req ReindexRequest) (Job, error) {
+	if !req.Normalize && !req.Embedding {
+		return Job{}, errors.New("at least one of normalize and embedding is required")
+	}
+
+	topic := "embedding_file"
+	if req.Normalize {
+		topic = "normalize_file"
+	}
+
+	base := func() *gorm.DB {
+		q := req.ReindexFilter.Apply(db.Instance().Model(&model.File{}))
+		if topic == "embedding_file" {
+			q = q.Where("file_path <> ''")
+		}
+		return q
+	}
+
+	var total int64
+	if err := base().Count(&total).Error; err != nil {
+		return Job{}, err
+	}
+
+	stages := []string{topic}
+	if topic == "normalize_file" {
+		stages = append(stages, "embedding_file")
+	}
+	job := jobs.create("reindex", stages...)
+	jobs.update(job.ID, func(j *Job) { j.Total = total })
+
+	go func() {
+		var lastID uint
+		var produced int64
+		for {
+			var files []model.File
+			err := base().
+				Select("id", "original_file_path", "file_path").
+				Where("id > ?", lastID).
+				Order("id").
+				Limit(reindexBatchSize).
+				Find(&files).Error
+			if err != nil {
+				log.Println("Reindex query error:", err)
+				jobs.finish(job.ID, produced, err)
+				return
+			}
+			if len(files) == 0 {
+				break
+			}
+
+			for _, f := range files {
+				path := f.FilePath
+				if topic == "normalize_file" {
+					path = f.OriginalFilePath
+				}
+				jobs.queued(job.ID, topic)
+				GlobalQueue.ProduceWait(topic, Payload{ID: f.ID, Path: path, JobID: job.ID})
+				produced++
+			}
+			lastID = files[len(files)-1].ID
+		}
+		jobs.finish(job.ID, produced, nil)
+		log.Printf("Reindex job %s enqueued %d files", job.ID, produced)
+	}()
+
+	snapshot, _ := GetJob(job.ID)
+	return snapshot, nil
+}
@@ -0,0 +1,53 @@
+package queue
+
+import (
+	"ThinkBank-backend/internal/db"
+	"ThinkBank-backend/internal/model"
+	"ThinkBank-backend/internal/service"
+	"log"
+	"time"
+)
+
+const stalledBatchSize = 500
+
+// ScheduleStalledFiles 周期性地把长时间停留在 pending / normalized 的文件重新推入队列。
+// 队列只存在于进程内存中，独立部署的 worker、进程重启以及命令行导入都依赖它接手未完成的文件
+func ScheduleStalledFiles(staleAfter, interval time.Duration) {
+	service.RegisterPeriodicService(func() {
+		requeueStalled(model.FileStatusPending, "normalize_file", "original_file_path", staleAfter)
+		requeueStalled(model.FileStatusNormalized, "embedding_file", "file_path", staleAfter)
+	}, interval)
+}
+
+func requeueStalled(status, topic, pathColumn string, staleAfter time.Duration) {
+	// 上一批尚未处理完时不重复推送
+	if GlobalQueue.Pending(topic) > 0 {
+		return
+	}
+
+	var files []Payload
+	err := db.Instance().Raw(`
+        UPDATE files SET updated_at = now()
+        WHERE id IN (
+            SELECT id FROM files
+            WHERE deleted_at IS NULL
+              AND status = ?
+              AND `+pathColumn+` <> ''
+              AND updated_at < ?
+            ORDER BY id
+            LIMIT ?
+        )
+        RETURNING id, `+pathColumn+` AS path
+    `, status, time.Now().Add(-staleAfter), stalledBatchSize).Scan(&files).Error
+	if err != nil {
+		log.Printf("Failed to scan stalled %s files: %v", status, err)
+		return
+	}
+
+	for _, f := range files {
+		GlobalQueue.Produce(topic, f)
+	}
+	if len(files) > 0 {
+		log.Printf("Requeued %d stalled %s files to %s", len(files), status, topic)
+	}
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -30,6 +31,12 @@ type FileService interface {
 
 	// List 列出该目录下所有文件名
 	List(subPath string) ([]FileInfo, error)
+
+	// Open 以流的方式读取指定子路径的文件，调用方负责关闭
+	Open(subPath string) (io.ReadCloser, error)
+
+	// SubPath 把 Put / Get 返回的 URL 还原为子路径
+	SubPath(url string) (string, bool)
 }
 
 // LocalFileService 本地存储实现
@@ -39,12 +46,15 @@ type LocalFileService struct {
 	BasePath string
 }
 
+// NewLocalFileService 创建本地存储，app 为 nil 时不注册静态路由（用于不提供 HTTP 的命令行任务）
 func NewLocalFileService(app fiber.Router, url string, route string, basePath string) *LocalFileService {
 	err := os.MkdirAll(basePath, os.ModePerm)
 	if err != nil {
 		return nil
 	}
-	app.Static(route, basePath)
+	if app != nil {
+		app.Static(route, basePath)
+	}
 	return &LocalFileService{URL: url, Route: route, BasePath: basePath}
 }
 
@@ -123,3 +133,17 @@ func (l *LocalFileService) Get(subPath string) (string, error) {
 	path := strings.ReplaceAll(filepath.Join(l.Route, subPath), "\\", "/")
 	return fmt.Sprintf("%s%s", l.URL, path), nil
 }
+
+// Open 打开文件用于流式读取
+func (l *LocalFileService) Open(subPath string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(l.BasePath, subPath))
+}
+
+// SubPath 把 Put / Get 返回的 URL 还原为相对于 BasePath 的子路径
+func (l *LocalFileService) SubPath(url string) (string, bool) {
+	prefix := l.URL + l.Route + "/"
+	if !strings.HasPrefix(url, prefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(url, prefix), true
+}
@@ -0,0 +1,317 @@
+package trip
+
+import (
+	"ThinkBank-backend/internal/db"
+	"ThinkBank-backend/internal/service"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+// Level 行程聚类层级及其默认聚类半径（米，Web Mercator）
+type Level struct {
+	Name   string
+	Radius float64
+}
+
+// Levels 全部层级，与 trg_geos_mark_trip_dirty 中的层级一致
+var Levels = []Level{
+	{"day", 20000},
+	{"week", 150000},
+	{"month", 800000},
+}
+
+// DefaultRadius 层级的默认聚类半径，预计算的结果按此半径生成
+func DefaultRadius(level string) (float64, bool) {
+	for _, l := range Levels {
+		if l.Name == level {
+			return l.Radius, true
+		}
+	}
+	return 0, false
+}
+
+const refreshBatchSize = 50
+
+// OutsideExcludedPlaces 位置不在任何排除行程的地点内，alias 为 geos 的表别名
+func OutsideExcludedPlaces(alias string) string {
+	return "NOT EXISTS (SELECT 1 FROM places p WHERE p.exclude_from_trips AND ST_Intersects(p.geom, " + alias + ".geom))"
+}
+
+// PlaceSQL 一组照片中出现最多的地名，ids 为照片 ID 数组的 SQL 表达式
+func PlaceSQL(ids string) string {
+	return `
+    SELECT g.country, g.region, g.city
+    FROM geos g
+    WHERE g.id = ANY(` + ids + `) AND g.country IS NOT NULL
+    GROUP BY g.country, g.region, g.city
+    ORDER BY COUNT(*) DESC, g.city
+    LIMIT 1`
+}
+
+var (
+	refreshMu     sync.Mutex
+	refreshSignal = make(chan struct{}, 1)
+)
+
+// Notify 有新的位置写入时调用，后台刷新任务会尽快重新计算受影响的时间段
+func Notify() {
+	select {
+	case refreshSignal <- struct{}{}:
+	default:
+	}
+}
+
+// StartRefresher 收到通知后等待 debounce 再批量刷新，并每隔 interval 兜底刷新一次
+func StartRefresher(debounce, interval time.Duration) {
+	go func() {
+		for range refreshSignal {
+			// 合并导入期间的大量通知
+			time.Sleep(debounce)
+			RefreshDirty()
+		}
+	}()
+	service.RegisterPeriodicService(RefreshDirty, interval)
+	Notify()
+}
+
+// RefreshDirty 重新计算所有被标记的时间段
+func RefreshDirty() {
+	refreshMu.Lock()
+	defer refreshMu.Unlock()
+
+	total := 0
+	for {
+		n, err := refreshBatch()
+		if err != nil {
+			log.Println("Trip cluster refresh failed:", err)
+			return
+		}
+		total += n
+		if n < refreshBatchSize {
+			break
+		}
+	}
+	if total > 0 {
+		log.Printf("Refreshed trip clusters for %d periods", total)
+	}
+}
+
+type dirtyPeriod struct {
+	Level  string
+	Period time.Time
+}
+
+// refreshBatch 取出一批标记并在同一事务内重算，多个进程同时刷新时用 SKIP LOCKED 错开
+func refreshBatch() (int, error) {
+	var periods []dirtyPeriod
+	err := db.Instance().Transaction(func(tx *gorm.DB) error {
+		err := tx.Raw(`
+            DELETE FROM trip_dirty_periods
+            WHERE (level, period) IN (
+                SELECT level, period FROM trip_dirty_periods
+                ORDER BY marked_at
+                LIMIT ?
+                FOR UPDATE SKIP LOCKED
+            )
+            RETURNING level, period
+        `, refreshBatchSize).Scan(&periods).Error
+		if err != nil {
+			return err
+		}
+		for _, p := range periods {
+			if err := refreshPeriod(tx, p); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return len(periods), err
+}
+
+type cluster struct {
+	CenterLon float64
+	CenterLat float64
+	Geom      string
+	StartTs   time.Time
+	EndTs     time.Time
+	PhotoIDs  pq.Int64Array `gorm:"type:bigint[]"`
+}
+
+// refreshPeriod 按默认半径重新聚类该时间段。与旧结果按照片重合度匹配，
+// 匹配上的行程原地更新以保留 ID（/trip/:id 的链接不会失效），其余删除或新建
+func refreshPeriod(tx *gorm.DB, p dirtyPeriod) error {
+	radius, ok := DefaultRadius(p.Level)
+	if !ok {
+		return nil
+	}
+	// 以日期字符串传参，避免 time.Time 经过会话时区换算后落到前一天
+	period := p.Period.Format("2006-01-02")
+
+	var existing []storedCluster
+	err := tx.Raw(`
+        SELECT c.id, COALESCE(array_agg(cp.file_id) FILTER (WHERE cp.file_id IS NOT NULL), '{}') AS photo_ids
+        FROM trip_clusters c
+        LEFT JOIN trip_cluster_photos cp ON cp.cluster_id = c.id
+        WHERE c.level = ? AND c.period = ?
+        GROUP BY c.id
+    `, p.Level, period).Scan(&existing).Error
+	if err != nil {
+		return err
+	}
+
+	// 层级来自固定表，可以直接拼入；范围条件用于走 create_at 索引，前后各留一天应对时区。
+	// 位于排除地点（如家、公司）内的照片不参与聚类
+	var clusters []cluster
+	err = tx.Raw(fmt.Sprintf(`
+        WITH pts AS (
+            SELECT id, create_at, geom3857
+            FROM geos
+            WHERE geom3857 IS NOT NULL
+              AND create_at >= ?::date - 1
+              AND create_at < ?::date + interval '1 %[1]s' + interval '1 day'
+              AND date_trunc('%[1]s', create_at)::date = ?::date
+              AND %[2]s
+        ),
+        groups AS (
+            SELECT row_number() OVER () AS n, geom
+            FROM (SELECT unnest(ST_ClusterWithin(geom3857, ?)) AS geom FROM pts) u
+        )
+        SELECT
+            ST_X(ST_Transform(ST_Centroid(c.geom), 4326)) AS center_lon,
+            ST_Y(ST_Transform(ST_Centroid(c.geom), 4326)) AS center_lat,
+            ST_AsEWKT(c.geom) AS geom,
+            MIN(p.create_at) AS start_ts,
+            MAX(p.create_at) AS end_ts,
+            array_agg(p.id ORDER BY p.create_at) AS photo_ids
+        FROM groups c
+        JOIN pts p ON ST_Intersects(p.geom3857, c.geom)
+        GROUP BY c.n, c.geom
+    `, p.Level, OutsideExcludedPlaces("geos")), period, period, period, radius).Scan(&clusters).Error
+	if err != nil {
+		return err
+	}
+
+	photoIDs := make([][]int64, len(clusters))
+	for i, c := range clusters {
+		photoIDs[i] = c.PhotoIDs
+	}
+	ids, stale := matchClusters(existing, photoIDs)
+	if len(stale) > 0 {
+		if err := tx.Exec("DELETE FROM trip_clusters WHERE id IN ?", stale).Error; err != nil {
+			return err
+		}
+	}
+
+	for i, c := range clusters {
+		id := ids[i]
+		if id == 0 {
+			err = tx.Raw(`
+                INSERT INTO trip_clusters
+                    (level, period, radius, center_lon, center_lat, geom3857, photo_count, start_ts, end_ts)
+                VALUES (?, ?, ?, ?, ?, ST_GeomFromEWKT(?), ?, ?, ?)
+                RETURNING id
+            `, p.Level, period, radius, c.CenterLon, c.CenterLat, c.Geom, len(c.PhotoIDs), c.StartTs, c.EndTs).
+				Scan(&id).Error
+		} else {
+			err = tx.Exec(`
+                UPDATE trip_clusters
+                SET radius = ?, center_lon = ?, center_lat = ?, geom3857 = ST_GeomFromEWKT(?),
+                    photo_count = ?, start_ts = ?, end_ts = ?, updated_at = now()
+                WHERE id = ?
+            `, radius, c.CenterLon, c.CenterLat, c.Geom, len(c.PhotoIDs), c.StartTs, c.EndTs, id).Error
+			if err == nil {
+				err = tx.Exec("DELETE FROM trip_cluster_photos WHERE cluster_id = ?", id).Error
+			}
+		}
+		if err != nil {
+			return err
+		}
+		err = tx.Exec(`
+            INSERT INTO trip_cluster_photos (cluster_id, file_id)
+            SELECT ?, unnest(?::bigint[])
+        `, id, c.PhotoIDs).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Exec(`
+            UPDATE trip_clusters c
+            SET (country, region, city) = (
+                SELECT p.country, p.region, p.city
+                FROM (`+PlaceSQL("?::bigint[]")+`) p
+            )
+            WHERE c.id = ?
+        `, c.PhotoIDs, id).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// storedCluster 已保存的行程及其照片
+type storedCluster struct {
+	ID       int64
+	PhotoIDs pq.Int64Array `gorm:"type:bigint[]"`
+}
+
+// matchClusters 为每个新聚类找到照片重合最多的旧行程，按重合数从大到小贪心匹配，
+// 每个旧行程最多匹配一次。返回各新聚类沿用的 ID（0 表示新建）与未被匹配的旧行程 ID
+func matchClusters(existing []storedCluster, clusters [][]int64) ([]int64, []int64) {
+	owner := make(map[int64]int, len(clusters))
+	for i, photos := range clusters {
+		for _, id := range photos {
+			owner[id] = i
+		}
+	}
+
+	type pair struct {
+		old, new, overlap int
+	}
+	var pairs []pair
+	for o, c := range existing {
+		overlap := make(map[int]int)
+		for _, id := range c.PhotoIDs {
+			if n, ok := owner[id]; ok {
+				overlap[n]++
+			}
+		}
+		for n, count := range overlap {
+			pairs = append(pairs, pair{o, n, count})
+		}
+	}
+	// 重合数相同时按下标排序，保证结果确定
+	sort.Slice(pairs, func(i, j int) bool {
+		a, b := pairs[i], pairs[j]
+		if a.overlap != b.overlap {
+			return a.overlap > b.overlap
+		}
+		if a.old != b.old {
+			return a.old < b.old
+		}
+		return a.new < b.new
+	})
+
+	ids := make([]int64, len(clusters))
+	used := make([]bool, len(existing))
+	for _, p := range pairs {
+		if used[p.old] || ids[p.new] != 0 {
+			continue
+		}
+		used[p.old] = true
+		ids[p.new] = existing[p.old].ID
+	}
+
+	var stale []int64
+	for o, c := range existing {
+		if !used[o] {
+			stale = append(stale, c.ID)
+		}
+	}
+	return ids, stale
+}
@@ -0,0 +1,65 @@
+package trip
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMatchClusters(t *testing.T) {
+	cases := []struct {
+		name      string
+		existing  []storedCluster
+		clusters  [][]int64
+		wantIDs   []int64
+		wantStale []int64
+	}{
+		{
+			name:     "first refresh",
+			clusters: [][]int64{{1, 2}, {3}},
+			wantIDs:  []int64{0, 0},
+		},
+		{
+			name:     "photo added keeps id",
+			existing: []storedCluster{{ID: 10, PhotoIDs: []int64{1, 2}}, {ID: 11, PhotoIDs: []int64{5, 6}}},
+			clusters: [][]int64{{5, 6, 7}, {1, 2}},
+			wantIDs:  []int64{11, 10},
+		},
+		{
+			name:      "all photos removed",
+			existing:  []storedCluster{{ID: 10, PhotoIDs: []int64{1, 2}}},
+			wantIDs:   []int64{},
+			wantStale: []int64{10},
+		},
+		{
+			name:     "split keeps id on larger part",
+			existing: []storedCluster{{ID: 10, PhotoIDs: []int64{1, 2, 3, 4, 5}}},
+			clusters: [][]int64{{1, 2}, {3, 4, 5}},
+			wantIDs:  []int64{0, 10},
+		},
+		{
+			name:      "merge keeps id of larger overlap",
+			existing:  []storedCluster{{ID: 10, PhotoIDs: []int64{1, 2}}, {ID: 11, PhotoIDs: []int64{3, 4, 5}}},
+			clusters:  [][]int64{{1, 2, 3, 4, 5}},
+			wantIDs:   []int64{11},
+			wantStale: []int64{10},
+		},
+		{
+			name:      "no overlap",
+			existing:  []storedCluster{{ID: 10, PhotoIDs: []int64{1, 2}}},
+			clusters:  [][]int64{{3, 4}},
+			wantIDs:   []int64{0},
+			wantStale: []int64{10},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ids, stale := matchClusters(c.existing, c.clusters)
+			if !slices.Equal(ids, c.wantIDs) {
+				t.Errorf("ids = %v, want %v", ids, c.wantIDs)
+			}
+			if !slices.Equal(stale, c.wantStale) {
+				t.Errorf("stale = %v, want %v", stale, c.wantStale)
+			}
+		})
+	}
+}
@@ -0,0 +1,368 @@
+package util
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"image"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// sRGB 原色在 D50 下的 XYZ（ICC 连接空间），与标准 sRGB 配置文件一致
+var srgbD50 = [3][3]float64{
+	{0.4360747, 0.3850649, 0.1430804},
+	{0.2225045, 0.7168786, 0.0606169},
+	{0.0139322, 0.0971045, 0.7141733},
+}
+
+// iccProfile 读取 JPEG APP2 或 PNG iCCP 中的 ICC 配置文件，没有时返回 nil
+func iccProfile(data []byte) []byte {
+	if bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")) {
+		return pngICCProfile(data)
+	}
+
+	// ICC 可能被拆分到多个 APP2 段，按序号拼接
+	const header = "ICC_PROFILE\x00"
+	chunks := make(map[int][]byte)
+	jpegSegments(data, func(marker byte, payload []byte) bool {
+		if marker == 0xE2 && len(payload) > len(header)+2 && bytes.HasPrefix(payload, []byte(header)) {
+			chunks[int(payload[len(header)])] = payload[len(header)+2:]
+		}
+		return true
+	})
+	if len(chunks) == 0 {
+		return nil
+	}
+	seqs := make([]int, 0, len(chunks))
+	for seq := range chunks {
+		seqs = append(seqs, seq)
+	}
+	sort.Ints(seqs)
+	var profile []byte
+	for _, seq := range seqs {
+		profile = append(profile, chunks[seq]...)
+	}
+	return profile
+}
+
+func pngICCProfile(data []byte) []byte {
+	for i := 8; i+12 <= len(data); {
+		size := int(binary.BigEndian.Uint32(data[i : i+4]))
+		typ := string(data[i+4 : i+8])
+		if i+12+size > len(data) || typ == "IDAT" {
+			return nil
+		}
+		chunk := data[i+8 : i+8+size]
+		i += 12 + size
+		if typ != "iCCP" {
+			continue
+		}
+
+		// 名称\0 + 压缩方式 + zlib 数据
+		nameEnd := bytes.IndexByte(chunk, 0)
+		if nameEnd < 0 || nameEnd+2 > len(chunk) {
+			return nil
+		}
+		r, err := zlib.NewReader(bytes.NewReader(chunk[nameEnd+2:]))
+		if err != nil {
+			return nil
+		}
+		profile, err := io.ReadAll(r)
+		if err != nil {
+			return nil
+		}
+		return profile
+	}
+	return nil
+}
+
+// toneCurve 配置文件的 TRC，把编码值转为线性值
+type toneCurve func(v float64) float64
+
+// rgbProfile 矩阵/TRC 形式的 RGB 配置文件
+type rgbProfile struct {
+	description string
+	matrix      [3][3]float64 // 线性 RGB 到 XYZ(D50)，按列为 r、g、b 原色
+	curves      [3]toneCurve
+}
+
+var errUnsupportedProfile = errors.New("unsupported ICC profile")
+
+// parseICC 解析矩阵/TRC 形式的 RGB 配置文件，基于 LUT 的配置文件返回 errUnsupportedProfile
+func parseICC(profile []byte) (*rgbProfile, error) {
+	if len(profile) < 132 || string(profile[16:20]) != "RGB " {
+		return nil, errUnsupportedProfile
+	}
+
+	tags := make(map[string][]byte)
+	count := int(binary.BigEndian.Uint32(profile[128:132]))
+	for n := 0; n < count; n++ {
+		entry := 132 + n*12
+		if entry+12 > len(profile) {
+			break
+		}
+		offset := int(binary.BigEndian.Uint32(profile[entry+4 : entry+8]))
+		size := int(binary.BigEndian.Uint32(profile[entry+8 : entry+12]))
+		if offset+size > len(profile) {
+			continue
+		}
+		tags[string(profile[entry:entry+4])] = profile[offset : offset+size]
+	}
+
+	p := &rgbProfile{description: iccDescription(tags["desc"])}
+	for col, sig := range []string{"rXYZ", "gXYZ", "bXYZ"} {
+		xyz := tags[sig]
+		if len(xyz) < 20 || string(xyz[:4]) != "XYZ " {
+			return nil, errUnsupportedProfile
+		}
+		for row := 0; row < 3; row++ {
+			p.matrix[row][col] = s15Fixed16(xyz[8+row*4:])
+		}
+	}
+	// 原色线性相关时无法表示 RGB 空间
+	if math.Abs(det3(p.matrix)) < 1e-6 {
+		return nil, errUnsupportedProfile
+	}
+	for i, sig := range []string{"rTRC", "gTRC", "bTRC"} {
+		curve, err := parseCurve(tags[sig])
+		if err != nil {
+			return nil, err
+		}
+		p.curves[i] = curve
+	}
+	return p, nil
+}
+
+func s15Fixed16(b []byte) float64 {
+	return float64(int32(binary.BigEndian.Uint32(b))) / 65536
+}
+
+// iccDescription 读取 desc 标签（v2 为 ASCII，v4 为 mluc 的 UTF-16）
+func iccDescription(tag []byte) string {
+	if len(tag) < 12 {
+		return ""
+	}
+	switch string(tag[:4]) {
+	case "desc":
+		n := int(binary.BigEndian.Uint32(tag[8:12]))
+		if 12+n > len(tag) {
+			return ""
+		}
+		return strings.TrimRight(string(tag[12:12+n]), "\x00")
+	case "mluc":
+		if len(tag) < 28 {
+			return ""
+		}
+		size := int(binary.BigEndian.Uint32(tag[20:24]))
+		offset := int(binary.BigEndian.Uint32(tag[24:28]))
+		if offset+size > len(tag) {
+			return ""
+		}
+		units := make([]uint16, size/2)
+		for i := range units {
+			units[i] = binary.BigEndian.Uint16(tag[offset+i*2:])
+		}
+		return string(utf16.Decode(units))
+	}
+	return ""
+}
+
+// parseCurve 解析 curv 或 para 类型的 TRC
+func parseCurve(tag []byte) (toneCurve, error) {
+	if len(tag) < 12 {
+		return nil, errUnsupportedProfile
+	}
+	switch string(tag[:4]) {
+	case "curv":
+		n := int(binary.BigEndian.Uint32(tag[8:12]))
+		switch {
+		case n == 0:
+			return func(v float64) float64 { return v }, nil
+		case n == 1 && len(tag) >= 14:
+			gamma := float64(binary.BigEndian.Uint16(tag[12:14])) / 256
+			return func(v float64) float64 { return math.Pow(v, gamma) }, nil
+		case len(tag) >= 12+n*2:
+			table := make([]float64, n)
+			for i := range table {
+				table[i] = float64(binary.BigEndian.Uint16(tag[12+i*2:])) / 65535
+			}
+			return func(v float64) float64 {
+				pos := v * float64(n-1)
+				i := int(pos)
+				if i >= n-1 {
+					return table[n-1]
+				}
+				frac := pos - float64(i)
+				return table[i]*(1-frac) + table[i+1]*frac
+			}, nil
+		}
+	case "para":
+		fn := binary.BigEndian.Uint16(tag[8:10])
+		want := map[uint16]int{0: 1, 1: 3, 2: 4, 3: 5, 4: 7}[fn]
+		if want == 0 || len(tag) < 12+want*4 {
+			return nil, errUnsupportedProfile
+		}
+		var p [7]float64
+		for i := 0; i < want; i++ {
+			p[i] = s15Fixed16(tag[12+i*4:])
+		}
+		g, a, b, c, d, e, f := p[0], p[1], p[2], p[3], p[4], p[5], p[6]
+		return func(x float64) float64 {
+			switch fn {
+			case 0:
+				return math.Pow(x, g)
+			case 1:
+				if x >= -b/a {
+					return math.Pow(a*x+b, g)
+				}
+				return 0
+			case 2:
+				if x >= -b/a {
+					return math.Pow(a*x+b, g) + c
+				}
+				return c
+			case 3:
+				if x >= d {
+					return math.Pow(a*x+b, g)
+				}
+				return c * x
+			default:
+				if x >= d {
+					return math.Pow(a*x+b, g) + e
+				}
+				return c*x + f
+			}
+		}, nil
+	}
+	return nil, errUnsupportedProfile
+}
+
+// isSRGB 配置文件本身就是 sRGB 时无需转换
+func (p *rgbProfile) isSRGB() bool {
+	if strings.Contains(strings.ToLower(p.description), "srgb") {
+		return true
+	}
+	for row := 0; row < 3; row++ {
+		for col := 0; col < 3; col++ {
+			if math.Abs(p.matrix[row][col]-srgbD50[row][col]) > 0.002 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// convertToSRGB 按 ICC 配置文件把图像转换到 sRGB，配置文件缺失、已是 sRGB 或不支持时原样返回
+func convertToSRGB(img image.Image, profile []byte) (image.Image, error) {
+	if len(profile) == 0 {
+		return img, nil
+	}
+	p, err := parseICC(profile)
+	if err != nil {
+		return img, err
+	}
+	if p.isSRGB() {
+		return img, nil
+	}
+
+	fromXYZ, ok := invert3(srgbD50)
+	if !ok {
+		return img, errUnsupportedProfile
+	}
+	toSRGB := mul3(fromXYZ, p.matrix)
+	for _, row := range toSRGB {
+		for _, v := range row {
+			if !finite(v) {
+				return img, errUnsupportedProfile
+			}
+		}
+	}
+
+	// 8 位输入的线性化查表，异常的曲线参数（如负数的非整数次幂）会得到 NaN
+	var linear [3][256]float64
+	for ch := 0; ch < 3; ch++ {
+		for v := 0; v < 256; v++ {
+			linear[ch][v] = p.curves[ch](float64(v) / 255)
+			if !finite(linear[ch][v]) {
+				return img, errUnsupportedProfile
+			}
+		}
+	}
+	// sRGB 编码查表
+	const steps = 4096
+	var encode [steps + 1]uint8
+	for i := range encode {
+		encode[i] = uint8(math.Round(srgbEncode(float64(i)/steps) * 255))
+	}
+
+	// 解码得到的 RGBA 图像会被原地修改
+	rgba := toRGBA(img)
+	for i := 0; i+3 < len(rgba.Pix); i += 4 {
+		r := linear[0][rgba.Pix[i]]
+		g := linear[1][rgba.Pix[i+1]]
+		b := linear[2][rgba.Pix[i+2]]
+		for ch := 0; ch < 3; ch++ {
+			v := toSRGB[ch][0]*r + toSRGB[ch][1]*g + toSRGB[ch][2]*b
+			rgba.Pix[i+ch] = encode[encodeIndex(v, steps)]
+		}
+	}
+	return rgba, nil
+}
+
+// encodeIndex 线性值在编码表中的下标，限制在 [0, steps]，NaN 视为 0
+func encodeIndex(v float64, steps int) int {
+	if !(v > 0) {
+		return 0
+	}
+	if v >= 1 {
+		return steps
+	}
+	return min(int(v*float64(steps)+0.5), steps)
+}
+
+func finite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
+func srgbEncode(v float64) float64 {
+	if v <= 0.0031308 {
+		return 12.92 * v
+	}
+	return 1.055*math.Pow(v, 1/2.4) - 0.055
+}
+
+func mul3(a, b [3][3]float64) [3][3]float64 {
+	var out [3][3]float64
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 3; k++ {
+				out[i][j] += a[i][k] * b[k][j]
+			}
+		}
+	}
+	return out
+}
+
+func det3(m [3][3]float64) float64 {
+	return m[0][0]*(m[1][1]*m[2][2]-m[1][2]*m[2][1]) -
+		m[0][1]*(m[1][0]*m[2][2]-m[1][2]*m[2][0]) +
+		m[0][2]*(m[1][0]*m[2][1]-m[1][1]*m[2][0])
+}
+
+// invert3 矩阵求逆，奇异矩阵返回 false
+func invert3(m [3][3]float64) ([3][3]float64, bool) {
+	det := det3(m)
+	if math.Abs(det) < 1e-12 || !finite(det) {
+		return [3][3]float64{}, false
+	}
+	return [3][3]float64{
+		{(m[1][1]*m[2][2] - m[1][2]*m[2][1]) / det, (m[0][2]*m[2][1] - m[0][1]*m[2][2]) / det, (m[0][1]*m[1][2] - m[0][2]*m[1][1]) / det},
+		{(m[1][2]*m[2][0] - m[1][0]*m[2][2]) / det, (m[0][0]*m[2][2] - m[0][2]*m[2][0]) / det, (m[0][2]*m[1][0] - m[0][0]*m[1][2]) / det},
+		{(m[1][0]*m[2][1] - m[1][1]*m[2][0]) / det, (m[0][1]*m[2][0] - m[0][0]*m[2][1]) / det, (m[0][0]*m[1][1] - m[0][1]*m[1][0]) / det},
+	}, true
+}
@@ -0,0 +1,122 @@
+package util
+
+import (
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// buildICC 生成只含 rXYZ/gXYZ/bXYZ 与 TRC 的 RGB 配置文件，primaries 按列为 r、g、b 原色的 XYZ
+func buildICC(primaries [3][3]float64, trc []byte) []byte {
+	s15 := func(v float64) []byte {
+		return binary.BigEndian.AppendUint32(nil, uint32(int32(math.Round(v*65536))))
+	}
+	type tag struct {
+		sig  string
+		data []byte
+	}
+	var tags []tag
+	for col, sig := range []string{"rXYZ", "gXYZ", "bXYZ"} {
+		data := []byte("XYZ \x00\x00\x00\x00")
+		for row := 0; row < 3; row++ {
+			data = append(data, s15(primaries[row][col])...)
+		}
+		tags = append(tags, tag{sig, data})
+	}
+	for _, sig := range []string{"rTRC", "gTRC", "bTRC"} {
+		tags = append(tags, tag{sig, trc})
+	}
+
+	profile := make([]byte, 128)
+	copy(profile[16:], "RGB ")
+	profile = binary.BigEndian.AppendUint32(profile, uint32(len(tags)))
+	offset := len(profile) + len(tags)*12
+	var data []byte
+	for _, t := range tags {
+		profile = append(profile, t.sig...)
+		profile = binary.BigEndian.AppendUint32(profile, uint32(offset+len(data)))
+		profile = binary.BigEndian.AppendUint32(profile, uint32(len(t.data)))
+		data = append(data, t.data...)
+	}
+	return append(profile, data...)
+}
+
+// paraCurve 生成 para 类型的 TRC
+func paraCurve(fn uint16, params ...float64) []byte {
+	out := []byte("para\x00\x00\x00\x00")
+	out = binary.BigEndian.AppendUint16(out, fn)
+	out = append(out, 0, 0)
+	for _, p := range params {
+		out = binary.BigEndian.AppendUint32(out, uint32(int32(math.Round(p*65536))))
+	}
+	return out
+}
+
+func TestConvertToSRGB(t *testing.T) {
+	// Display P3 原色在 D50 下的 XYZ
+	p3 := [3][3]float64{
+		{0.5151, 0.2920, 0.1571},
+		{0.2412, 0.6922, 0.0666},
+		{-0.0011, 0.0419, 0.7841},
+	}
+	singular := [3][3]float64{
+		{0.4361, 0.4361, 0.1431},
+		{0.2225, 0.2225, 0.0606},
+		{0.0139, 0.0139, 0.7142},
+	}
+	linear := []byte("curv\x00\x00\x00\x00\x00\x00\x00\x00")
+
+	cases := []struct {
+		name    string
+		profile []byte
+		wantErr error
+	}{
+		{"display p3", buildICC(p3, paraCurve(0, 2.2)), nil},
+		{"singular matrix", buildICC(singular, linear), errUnsupportedProfile},
+		{"zero matrix", buildICC([3][3]float64{}, linear), errUnsupportedProfile},
+		{"negative base in para curve", buildICC(p3, paraCurve(1, 2.4, -1, 0)), errUnsupportedProfile},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+			img.Set(0, 0, color.RGBA{255, 0, 0, 255})
+			img.Set(1, 0, color.RGBA{10, 200, 30, 255})
+
+			out, err := convertToSRGB(img, c.profile)
+			if !errors.Is(err, c.wantErr) {
+				t.Fatalf("err = %v, want %v", err, c.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			// P3 的纯红超出 sRGB 色域，转换后绿、蓝被截断为 0
+			if got := out.At(0, 0).(color.RGBA); got.R != 255 || got.G != 0 || got.B != 0 {
+				t.Errorf("pure red = %v, want clipped to sRGB red", got)
+			}
+		})
+	}
+}
+
+func TestEncodeIndex(t *testing.T) {
+	cases := []struct {
+		v    float64
+		want int
+	}{
+		{math.NaN(), 0},
+		{math.Inf(-1), 0},
+		{-0.5, 0},
+		{0, 0},
+		{0.5, 2048},
+		{1, 4096},
+		{math.Inf(1), 4096},
+		{1e300, 4096},
+	}
+	for _, c := range cases {
+		if got := encodeIndex(c.v, 4096); got != c.want {
+			t.Errorf("encodeIndex(%v) = %d, want %d", c.v, got, c.want)
+		}
+	}
+}
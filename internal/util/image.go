@@ -11,22 +11,39 @@ import (
 	"io"
 	"log"
 	"path/filepath"
+	"strconv"
 	"strings"
-	"time"
 
 	"archive/zip"
 
 	"github.com/jdeng/goheif"
-	"github.com/rwcarlsen/goexif/exif"
+	"github.com/jdeng/goheif/heif"
+	"github.com/jdeng/goheif/heif/bmff"
 )
 
-type ExifInfo struct {
-	Latitude  float64
-	Longitude float64
-	CreateAt  time.Time
+// 规范化输出的元数据策略
+const (
+	MetadataStrip    = "strip"    // 不保留任何元数据（默认）
+	MetadataPreserve = "preserve" // 保留原图 EXIF（含定位）
+)
+
+var metadataPolicy = MetadataStrip
+
+// ConfigureMetadataPolicy 设置规范化输出是否保留原图 EXIF，空字符串使用默认值
+func ConfigureMetadataPolicy(policy string) {
+	switch policy {
+	case "":
+		metadataPolicy = MetadataStrip
+	case MetadataStrip, MetadataPreserve:
+		metadataPolicy = policy
+	default:
+		log.Fatalf("Invalid NORMALIZE_METADATA: %s", policy)
+	}
+	log.Println("Normalized image metadata:", metadataPolicy)
 }
 
-func ProcessImageToJPEG(data []byte, ext string) ([]byte, *ExifInfo, error) {
+// ProcessImageToJPEG 转码为 JPEG，同时提取原图元数据
+func ProcessImageToJPEG(data []byte, ext string) ([]byte, *ImageMetadata, error) {
 	ext = strings.ToLower(ext)
 
 	switch ext {
@@ -39,30 +56,103 @@ func ProcessImageToJPEG(data []byte, ext string) ([]byte, *ExifInfo, error) {
 	}
 }
 
-func encodeJPEGFromHEIC(data []byte) ([]byte, *ExifInfo, error) {
-	exifInfo := extractHEICExifInfo(data)
+func encodeJPEGFromHEIC(data []byte) ([]byte, *ImageMetadata, error) {
+	exifBytes, err := goheif.ExtractExif(bytes.NewReader(data))
+	if err != nil {
+		log.Println("Warning: no EXIF found", err)
+	}
+	meta := ExtractMetadata(data, exifBytes)
 	img, err := goheif.Decode(bytes.NewReader(data))
 	if err != nil {
 		return nil, nil, err
 	}
-	var buf bytes.Buffer
-	err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90})
-	return buf.Bytes(), exifInfo, err
+	// HEIF 的方向由 irot / imir 决定，EXIF Orientation 仅供参考；ICC 位于 colr 中
+	var props []bmff.Box
+	if it, err := heif.Open(bytes.NewReader(data)).PrimaryItem(); err == nil {
+		props = it.Properties
+	}
+	return encodeJPEG(img, meta, heifOrientation(props), heifICCProfile(props), exifTIFF(exifBytes))
 }
 
-func encodeJPEGFromImageData(data []byte) ([]byte, *ExifInfo, error) {
-	exifInfo := extractExifInfo(data)
+// heifOrientation 按属性顺序组合 irot（逆时针 90° 的次数）与 imir，换算成 EXIF Orientation。
+// imir 按 libheif 的约定：0 为上下翻转，1 为左右翻转
+func heifOrientation(props []bmff.Box) int {
+	// 状态表示先左右翻转 flip，再逆时针旋转 rot 次
+	rot, flip := 0, false
+	for _, p := range props {
+		switch v := p.(type) {
+		case *bmff.ImageRotation:
+			rot += int(v.Angle)
+		case *bmff.ImageMirror:
+			if v.Mirror == bmff.MirrorHorizontal {
+				rot = -rot
+			} else {
+				// 上下翻转等于左右翻转后旋转 180°
+				rot = 2 - rot
+			}
+			flip = !flip
+		}
+	}
+	rot = (rot%4 + 4) % 4
+	if flip {
+		return [4]int{2, 5, 4, 7}[rot]
+	}
+	return [4]int{1, 8, 3, 6}[rot]
+}
+
+// heifICCProfile 读取 colr 中的 ICC（prof / rICC），nclx 等其他类型返回 nil
+func heifICCProfile(props []bmff.Box) []byte {
+	for _, p := range props {
+		if !p.Type().EqualString("colr") {
+			continue
+		}
+		body, err := io.ReadAll(p.Body())
+		if err != nil || len(body) <= 4 {
+			continue
+		}
+		if kind := string(body[:4]); kind == "prof" || kind == "rICC" {
+			return body[4:]
+		}
+	}
+	return nil
+}
+
+func encodeJPEGFromImageData(data []byte) ([]byte, *ImageMetadata, error) {
+	meta := ExtractMetadata(data, data)
 	img, _, err := image.Decode(bytes.NewReader(data))
 	if err != nil {
 		return nil, nil, err
 	}
+	orientation, _ := strconv.Atoi(meta.Fields["orientation"])
+	return encodeJPEG(img, meta, orientation, iccProfile(data), exifTIFF(data))
+}
+
+// encodeJPEG 转换到 sRGB 并按方向摆正后编码，宽高以输出图像为准。
+// 按元数据策略决定是否写回原图 EXIF
+func encodeJPEG(img image.Image, meta *ImageMetadata, orientation int, profile, tiffData []byte) ([]byte, *ImageMetadata, error) {
+	img, err := convertToSRGB(img, profile)
+	if err != nil {
+		log.Println("Skipping colour conversion:", err)
+	}
+	img = applyOrientation(img, orientation)
+
+	bounds := img.Bounds()
+	meta.set("width", strconv.Itoa(bounds.Dx()))
+	meta.set("height", strconv.Itoa(bounds.Dy()))
+
 	var buf bytes.Buffer
-	err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90})
-	return buf.Bytes(), exifInfo, err
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90}); err != nil {
+		return nil, nil, err
+	}
+	out := buf.Bytes()
+	if metadataPolicy == MetadataPreserve && len(tiffData) > 0 {
+		out = insertEXIF(out, resetOrientation(tiffData))
+	}
+	return out, meta, nil
 }
 
 // livp 内部递归处理图片或 heic
-func extractImageFromLivpRecursive(data []byte) ([]byte, *ExifInfo, error) {
+func extractImageFromLivpRecursive(data []byte) ([]byte, *ImageMetadata, error) {
 	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
 	if err != nil {
 		return nil, nil, err
@@ -97,34 +187,3 @@ func extractImageFromLivpRecursive(data []byte) ([]byte, *ExifInfo, error) {
 
 	return nil, nil, fmt.Errorf("no image found in livp")
 }
-
-func extractExifInfo(data []byte) *ExifInfo {
-	var err error
-	x, err := exif.Decode(bytes.NewReader(data))
-	if err != nil {
-		log.Println("Error occurs while extracting EXIF:", err)
-		return nil
-	}
-
-	tm, err := x.DateTime()
-	lat, long, err := x.LatLong()
-	if err != nil {
-		log.Println("Error occurs while extracting EXIF DateTime and LatLong:", err)
-		return nil
-	}
-
-	exifInfo := new(ExifInfo)
-	exifInfo.Latitude = lat
-	exifInfo.Longitude = long
-	exifInfo.CreateAt = tm
-
-	return exifInfo
-}
-
-func extractHEICExifInfo(data []byte) *ExifInfo {
-	exifBytes, err := goheif.ExtractExif(bytes.NewReader(data))
-	if err != nil {
-		log.Println("Warning: no EXIF found", err)
-	}
-	return extractExifInfo(exifBytes)
-}
@@ -0,0 +1,71 @@
+package util
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/jdeng/goheif/heif/bmff"
+)
+
+func TestHEIFOrientation(t *testing.T) {
+	// 2x3 图像，每个像素颜色不同
+	src := image.NewRGBA(image.Rect(0, 0, 2, 3))
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 2; x++ {
+			src.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), A: 255})
+		}
+	}
+	rotate := func(n uint8) bmff.Box { return &bmff.ImageRotation{Angle: n} }
+	mirror := func(m uint8) bmff.Box { return &bmff.ImageMirror{Mirror: m} }
+
+	cases := []struct {
+		name  string
+		props []bmff.Box
+	}{
+		{"none", nil},
+		{"rotate 90", []bmff.Box{rotate(1)}},
+		{"rotate 180", []bmff.Box{rotate(2)}},
+		{"rotate 270", []bmff.Box{rotate(3)}},
+		{"mirror left-right", []bmff.Box{mirror(bmff.MirrorHorizontal)}},
+		{"mirror top-bottom", []bmff.Box{mirror(bmff.MirrorVertical)}},
+		{"rotate 90 then mirror left-right", []bmff.Box{rotate(1), mirror(bmff.MirrorHorizontal)}},
+		{"rotate 90 then mirror top-bottom", []bmff.Box{rotate(1), mirror(bmff.MirrorVertical)}},
+		{"mirror left-right then rotate 90", []bmff.Box{mirror(bmff.MirrorHorizontal), rotate(1)}},
+		{"rotate 270 then mirror left-right", []bmff.Box{rotate(3), mirror(bmff.MirrorHorizontal)}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// 逐个应用变换作为期望结果
+			var want image.Image = src
+			for _, p := range c.props {
+				switch v := p.(type) {
+				case *bmff.ImageRotation:
+					for i := uint8(0); i < v.Angle; i++ {
+						want = applyOrientation(want, 8)
+					}
+				case *bmff.ImageMirror:
+					if v.Mirror == bmff.MirrorHorizontal {
+						want = applyOrientation(want, 2)
+					} else {
+						want = applyOrientation(want, 4)
+					}
+				}
+			}
+
+			orientation := heifOrientation(c.props)
+			got := applyOrientation(src, orientation)
+			if got.Bounds() != want.Bounds() {
+				t.Fatalf("orientation %d: bounds %v, want %v", orientation, got.Bounds(), want.Bounds())
+			}
+			b := want.Bounds()
+			for y := b.Min.Y; y < b.Max.Y; y++ {
+				for x := b.Min.X; x < b.Max.X; x++ {
+					if got.At(x, y) != want.At(x, y) {
+						t.Fatalf("orientation %d: pixel (%d, %d) = %v, want %v", orientation, x, y, got.At(x, y), want.At(x, y))
+					}
+				}
+			}
+		})
+	}
+}
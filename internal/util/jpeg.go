@@ -0,0 +1,199 @@
+package util
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+const exifHeader = "Exif\x00\x00"
+
+// jpegSegments 依次遍历 JPEG 在图像数据之前的各个段，fn 返回 false 时停止
+func jpegSegments(data []byte, fn func(marker byte, payload []byte) bool) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return
+	}
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return
+		}
+		marker := data[i+1]
+		if marker == 0xDA || marker == 0xD9 {
+			// 图像数据开始，之后不再有元数据段
+			return
+		}
+		size := int(binary.BigEndian.Uint16(data[i+2 : i+4]))
+		if size < 2 || i+2+size > len(data) {
+			return
+		}
+		if !fn(marker, data[i+4:i+2+size]) {
+			return
+		}
+		i += 2 + size
+	}
+}
+
+// exifTIFF 取出 EXIF 的 TIFF 数据：JPEG 从 APP1 段读取，其他格式（如 HEIC 取出的 EXIF 块）查找 TIFF 头
+func exifTIFF(data []byte) []byte {
+	var tiffData []byte
+	jpegSegments(data, func(marker byte, payload []byte) bool {
+		if marker == 0xE1 && bytes.HasPrefix(payload, []byte(exifHeader)) {
+			tiffData = payload[len(exifHeader):]
+		}
+		return tiffData == nil
+	})
+	if tiffData != nil || (len(data) > 2 && data[0] == 0xFF && data[1] == 0xD8) {
+		return tiffData
+	}
+
+	for _, magic := range [][]byte{[]byte("II*\x00"), []byte("MM\x00*")} {
+		if i := bytes.Index(data, magic); i >= 0 {
+			return data[i:]
+		}
+	}
+	return nil
+}
+
+// resetOrientation 把 IFD0 中的 Orientation 改为 1，像素已按原方向旋转后使用
+func resetOrientation(tiffData []byte) []byte {
+	if len(tiffData) < 8 {
+		return tiffData
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if tiffData[0] == 'M' {
+		order = binary.BigEndian
+	}
+
+	out := append([]byte(nil), tiffData...)
+	ifd := int(order.Uint32(out[4:8]))
+	if ifd+2 > len(out) {
+		return out
+	}
+	count := int(order.Uint16(out[ifd : ifd+2]))
+	for n := 0; n < count; n++ {
+		entry := ifd + 2 + n*12
+		if entry+12 > len(out) {
+			break
+		}
+		if order.Uint16(out[entry:entry+2]) == 0x0112 {
+			// SHORT 类型，值直接存放在条目中
+			order.PutUint16(out[entry+8:entry+10], 1)
+			break
+		}
+	}
+	return out
+}
+
+// insertEXIF 在 JPEG 的 SOI 之后插入 APP1 EXIF 段
+func insertEXIF(jpegData, tiffData []byte) []byte {
+	payload := append([]byte(exifHeader), tiffData...)
+	if len(payload)+2 > 0xFFFF || len(jpegData) < 2 {
+		// 单个段无法容纳，放弃写入
+		return jpegData
+	}
+
+	out := make([]byte, 0, len(jpegData)+len(payload)+4)
+	out = append(out, jpegData[:2]...)
+	out = append(out, 0xFF, 0xE1, byte((len(payload)+2)>>8), byte(len(payload)+2))
+	out = append(out, payload...)
+	return append(out, jpegData[2:]...)
+}
+
+const xmpHeader = "http://ns.adobe.com/xap/1.0/\x00"
+
+// StripGPS 去除 JPEG 中的定位信息：清空 EXIF 的 GPS IFD，并丢弃带有 GPS 字段的 XMP 段。
+// 非 JPEG 数据原样返回，ok 为 false
+func StripGPS(data []byte) (out []byte, ok bool) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return data, false
+	}
+
+	out = make([]byte, 0, len(data))
+	out = append(out, data[:2]...)
+	i := 2
+	for i+4 <= len(data) && data[i] == 0xFF {
+		marker := data[i+1]
+		if marker == 0xDA || marker == 0xD9 {
+			break
+		}
+		size := int(binary.BigEndian.Uint16(data[i+2 : i+4]))
+		if size < 2 || i+2+size > len(data) {
+			break
+		}
+		segment := data[i : i+2+size]
+		payload := segment[4:]
+		i += 2 + size
+
+		if marker == 0xE1 && bytes.HasPrefix(payload, []byte(xmpHeader)) && bytes.Contains(payload, []byte("GPS")) {
+			continue
+		}
+		if marker == 0xE1 && bytes.HasPrefix(payload, []byte(exifHeader)) {
+			tiffData := stripGPSIFD(payload[len(exifHeader):])
+			segment = append(append([]byte(nil), segment[:4+len(exifHeader)]...), tiffData...)
+		}
+		out = append(out, segment...)
+	}
+	return append(out, data[i:]...), true
+}
+
+// tiffTypeSizes TIFF 各数据类型的字节数
+var tiffTypeSizes = map[uint16]int{1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8}
+
+// stripGPSIFD 清零 GPS IFD 的全部条目与数据，并从 IFD0 中删除 GPSInfo 条目，长度保持不变
+func stripGPSIFD(tiffData []byte) []byte {
+	if len(tiffData) < 8 {
+		return tiffData
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if tiffData[0] == 'M' {
+		order = binary.BigEndian
+	}
+
+	out := append([]byte(nil), tiffData...)
+	ifd := int(order.Uint32(out[4:8]))
+	if ifd+2 > len(out) {
+		return out
+	}
+	count := int(order.Uint16(out[ifd : ifd+2]))
+	// 条目之后紧跟下一个 IFD 的偏移
+	end := min(ifd+2+count*12+4, len(out))
+	for n := 0; n < count; n++ {
+		entry := ifd + 2 + n*12
+		if entry+12 > len(out) {
+			break
+		}
+		if order.Uint16(out[entry:entry+2]) != 0x8825 {
+			continue
+		}
+		wipeIFD(out, order, int(order.Uint32(out[entry+8:entry+12])))
+
+		// 后续条目与下一 IFD 偏移前移一个条目
+		copy(out[entry:], out[entry+12:end])
+		clear(out[end-12 : end])
+		order.PutUint16(out[ifd:ifd+2], uint16(count-1))
+		break
+	}
+	return out
+}
+
+// wipeIFD 清零 IFD 条目及其引用的数据
+func wipeIFD(tiffData []byte, order binary.ByteOrder, ifd int) {
+	if ifd < 8 || ifd+2 > len(tiffData) {
+		return
+	}
+	count := int(order.Uint16(tiffData[ifd : ifd+2]))
+	for n := 0; n < count; n++ {
+		entry := ifd + 2 + n*12
+		if entry+12 > len(tiffData) {
+			break
+		}
+		size := tiffTypeSizes[order.Uint16(tiffData[entry+2:entry+4])] * int(order.Uint32(tiffData[entry+4:entry+8]))
+		if size > 4 {
+			offset := int(order.Uint32(tiffData[entry+8 : entry+12]))
+			if offset >= 8 && offset+size <= len(tiffData) {
+				clear(tiffData[offset : offset+size])
+			}
+		}
+		clear(tiffData[entry : entry+12])
+	}
+	order.PutUint16(tiffData[ifd:ifd+2], 0)
+}
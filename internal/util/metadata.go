@@ -0,0 +1,348 @@
+package util
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rwcarlsen/goexif/exif"
+	"github.com/rwcarlsen/goexif/tiff"
+)
+
+// ImageMetadata 从图片中提取的元数据，拍摄时间与位置互不依赖，缺失时为 nil
+type ImageMetadata struct {
+	TakenAt   *time.Time
+	Latitude  *float64
+	Longitude *float64
+	Fields    map[string]string // 写入 File.Metadata 的字段
+}
+
+// HasLocation 是否包含经纬度
+func (m *ImageMetadata) HasLocation() bool {
+	return m != nil && m.Latitude != nil && m.Longitude != nil
+}
+
+func (m *ImageMetadata) set(key, val string) {
+	val = strings.TrimSpace(strings.Trim(val, "\x00"))
+	if val == "" {
+		return
+	}
+	if m.Fields == nil {
+		m.Fields = make(map[string]string)
+	}
+	m.Fields[key] = val
+}
+
+// setDefault 仅在字段尚不存在时写入，EXIF 优先于 XMP 与 IPTC
+func (m *ImageMetadata) setDefault(key, val string) {
+	if _, ok := m.Fields[key]; !ok {
+		m.set(key, val)
+	}
+}
+
+// exifFields EXIF 标签与 Metadata 字段名的对应关系
+var exifFields = map[exif.FieldName]string{
+	exif.Make:                  "make",
+	exif.Model:                 "model",
+	exif.LensMake:              "lensMake",
+	exif.LensModel:             "lensModel",
+	exif.FNumber:               "fNumber",
+	exif.ISOSpeedRatings:       "iso",
+	exif.FocalLength:           "focalLength",
+	exif.FocalLengthIn35mmFilm: "focalLength35mm",
+	exif.ExposureBiasValue:     "exposureBias",
+	exif.ExposureProgram:       "exposureProgram",
+	exif.MeteringMode:          "meteringMode",
+	exif.Flash:                 "flash",
+	exif.WhiteBalance:          "whiteBalance",
+	exif.Orientation:           "orientation",
+	exif.PixelXDimension:       "width",
+	exif.PixelYDimension:       "height",
+	exif.Software:              "software",
+	exif.Artist:                "artist",
+	exif.Copyright:             "copyright",
+	exif.ImageDescription:      "description",
+	exif.GPSAltitude:           "altitude",
+}
+
+// ExtractMetadata 从原始文件数据中提取 EXIF、XMP 与 IPTC 元数据。
+// exifData 为 EXIF 所在的数据，JPEG 即 data 本身，HEIC 需单独取出，为空表示没有 EXIF
+func ExtractMetadata(data, exifData []byte) *ImageMetadata {
+	meta := &ImageMetadata{}
+	if len(exifData) > 0 {
+		extractExif(meta, exifData)
+	}
+	extractXMP(meta, data)
+	extractIPTC(meta, data)
+	return meta
+}
+
+func extractExif(meta *ImageMetadata, data []byte) {
+	x, err := exif.Decode(bytes.NewReader(data))
+	if err != nil {
+		log.Println("Error occurs while extracting EXIF:", err)
+		return
+	}
+
+	if tm, err := x.DateTime(); err == nil && !tm.IsZero() {
+		meta.TakenAt = &tm
+		meta.set("dateTime", tm.Format(time.RFC3339))
+	}
+	if lat, lng, err := x.LatLong(); err == nil && validLatLng(lat, lng) {
+		meta.Latitude, meta.Longitude = &lat, &lng
+		meta.set("latitude", strconv.FormatFloat(lat, 'f', -1, 64))
+		meta.set("longitude", strconv.FormatFloat(lng, 'f', -1, 64))
+	}
+
+	for name, key := range exifFields {
+		if tag, err := x.Get(name); err == nil {
+			meta.set(key, tagValue(tag))
+		}
+	}
+	// 曝光时间同时保存秒数（便于范围过滤）与习惯写法 1/250
+	if tag, err := x.Get(exif.ExposureTime); err == nil {
+		if num, den, err := tag.Rat2(0); err == nil && num > 0 && den > 0 {
+			meta.set("exposureTime", strconv.FormatFloat(float64(num)/float64(den), 'f', -1, 64))
+			if num < den {
+				meta.set("shutterSpeed", "1/"+strconv.FormatFloat(float64(den)/float64(num), 'f', 0, 64))
+			} else {
+				meta.set("shutterSpeed", strconv.FormatFloat(float64(num)/float64(den), 'f', -1, 64))
+			}
+		}
+	}
+}
+
+// validLatLng 过滤越界与未写入定位时常见的 (0, 0)
+func validLatLng(lat, lng float64) bool {
+	return lat >= -90 && lat <= 90 && lng >= -180 && lng <= 180 && (lat != 0 || lng != 0)
+}
+
+// tagValue 把 EXIF 标签转成字符串，有理数转为小数
+func tagValue(tag *tiff.Tag) string {
+	switch tag.Format() {
+	case tiff.StringVal:
+		s, _ := tag.StringVal()
+		return s
+	case tiff.IntVal:
+		n, err := tag.Int64(0)
+		if err != nil {
+			return ""
+		}
+		return strconv.FormatInt(n, 10)
+	case tiff.RatVal:
+		num, den, err := tag.Rat2(0)
+		if err != nil || den == 0 {
+			return ""
+		}
+		return strconv.FormatFloat(float64(num)/float64(den), 'f', -1, 64)
+	case tiff.FloatVal:
+		f, err := tag.Float(0)
+		if err != nil {
+			return ""
+		}
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	default:
+		return ""
+	}
+}
+
+// extractXMP 在文件中查找 XMP 包（JPEG APP1、HEIC mime item 中均为明文 XML）
+func extractXMP(meta *ImageMetadata, data []byte) {
+	start := bytes.Index(data, []byte("<x:xmpmeta"))
+	if start < 0 {
+		return
+	}
+	end := bytes.Index(data[start:], []byte("</x:xmpmeta>"))
+	if end < 0 {
+		return
+	}
+	packet := data[start : start+end+len("</x:xmpmeta>")]
+
+	values, err := parseXMP(packet)
+	if err != nil {
+		log.Println("Error occurs while extracting XMP:", err)
+		return
+	}
+	for _, field := range []struct{ prop, key string }{
+		{"Rating", "rating"},
+		{"Label", "label"},
+		{"title", "title"},
+		{"description", "description"},
+		{"creator", "creator"},
+		{"rights", "copyright"},
+		{"City", "city"},
+		{"State", "state"},
+		{"Country", "country"},
+		{"Headline", "headline"},
+		{"Lens", "lensModel"},
+		{"LensModel", "lensModel"},
+	} {
+		if v := values[field.prop]; len(v) > 0 {
+			meta.setDefault(field.key, strings.Join(v, ", "))
+		}
+	}
+	if keywords := values["subject"]; len(keywords) > 0 {
+		meta.set("keywords", joinKeywords(meta.Fields["keywords"], keywords))
+	}
+}
+
+// parseXMP 按属性本地名收集 XMP 中的值，同时支持属性写法与 rdf:Bag/Seq/Alt 列表
+func parseXMP(packet []byte) (map[string][]string, error) {
+	values := make(map[string][]string)
+	dec := xml.NewDecoder(bytes.NewReader(packet))
+	var stack []string
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			return values, nil
+		}
+		if err != nil {
+			return values, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			stack = append(stack, t.Name.Local)
+			if t.Name.Local == "Description" {
+				for _, attr := range t.Attr {
+					if attr.Name.Space != "xmlns" && attr.Name.Local != "about" {
+						values[attr.Name.Local] = append(values[attr.Name.Local], attr.Value)
+					}
+				}
+			}
+		case xml.EndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			text := strings.TrimSpace(string(t))
+			if text == "" || len(stack) == 0 {
+				continue
+			}
+			// 列表项归属于 Bag/Seq/Alt 外层的属性
+			prop := stack[len(stack)-1]
+			if prop == "li" && len(stack) >= 3 {
+				prop = stack[len(stack)-3]
+			}
+			values[prop] = append(values[prop], text)
+		}
+	}
+}
+
+// IPTC-IIM 记录 2 中使用的数据集
+var iptcFields = map[byte]string{
+	5:   "title",
+	80:  "creator",
+	90:  "city",
+	95:  "state",
+	101: "country",
+	105: "headline",
+	116: "copyright",
+	120: "caption",
+}
+
+// extractIPTC 读取 JPEG APP13（Photoshop 3.0）中 8BIM 0x0404 资源里的 IPTC-IIM
+func extractIPTC(meta *ImageMetadata, data []byte) {
+	block := findIPTCBlock(data)
+	if block == nil {
+		return
+	}
+
+	var keywords []string
+	for i := 0; i+5 <= len(block); {
+		if block[i] != 0x1C {
+			break
+		}
+		record, dataset := block[i+1], block[i+2]
+		size := int(binary.BigEndian.Uint16(block[i+3 : i+5]))
+		i += 5
+		if size&0x8000 != 0 || i+size > len(block) {
+			// 扩展长度格式只用于超大数据集，这里不需要
+			break
+		}
+		val := string(block[i : i+size])
+		i += size
+
+		if record != 2 {
+			continue
+		}
+		if dataset == 25 {
+			keywords = append(keywords, val)
+			continue
+		}
+		if key, ok := iptcFields[dataset]; ok {
+			meta.setDefault(key, val)
+		}
+	}
+	if len(keywords) > 0 {
+		meta.set("keywords", joinKeywords(meta.Fields["keywords"], keywords))
+	}
+}
+
+func findIPTCBlock(data []byte) []byte {
+	const header = "Photoshop 3.0\x00"
+	var block []byte
+	jpegSegments(data, func(marker byte, payload []byte) bool {
+		if marker == 0xED && bytes.HasPrefix(payload, []byte(header)) {
+			block = find8BIM(payload[len(header):], 0x0404)
+		}
+		return block == nil
+	})
+	return block
+}
+
+// find8BIM 在 Photoshop 图像资源中查找指定 ID 的资源
+func find8BIM(res []byte, id uint16) []byte {
+	for i := 0; i+12 <= len(res); {
+		if !bytes.Equal(res[i:i+4], []byte("8BIM")) {
+			return nil
+		}
+		resID := binary.BigEndian.Uint16(res[i+4 : i+6])
+		// 名称为 Pascal 字符串，连同长度字节补齐到偶数
+		nameLen := int(res[i+6])
+		pos := i + 6 + nameLen + 1
+		if pos%2 != 0 {
+			pos++
+		}
+		if pos+4 > len(res) {
+			return nil
+		}
+		size := int(binary.BigEndian.Uint32(res[pos : pos+4]))
+		pos += 4
+		if pos+size > len(res) {
+			return nil
+		}
+		if resID == id {
+			return res[pos : pos+size]
+		}
+		i = pos + size
+		if size%2 != 0 {
+			i++
+		}
+	}
+	return nil
+}
+
+// joinKeywords 合并关键词并去重
+func joinKeywords(existing string, keywords []string) string {
+	seen := make(map[string]struct{})
+	var merged []string
+	for _, k := range append(strings.Split(existing, ","), keywords...) {
+		k = strings.TrimSpace(k)
+		if k == "" {
+			continue
+		}
+		if _, ok := seen[strings.ToLower(k)]; ok {
+			continue
+		}
+		seen[strings.ToLower(k)] = struct{}{}
+		merged = append(merged, k)
+	}
+	return strings.Join(merged, ", ")
+}
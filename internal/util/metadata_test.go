@@ -0,0 +1,276 @@
+package util
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+	"testing"
+	"time"
+)
+
+// tiffEntry 测试用 TIFF IFD 项，data 为小端序编码后的值
+type tiffEntry struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	data  []byte
+}
+
+func asciiEntry(tag uint16, s string) tiffEntry {
+	return tiffEntry{tag, 2, uint32(len(s) + 1), append([]byte(s), 0)}
+}
+
+func shortEntry(tag uint16, v uint16) tiffEntry {
+	return tiffEntry{tag, 3, 1, binary.LittleEndian.AppendUint16(nil, v)}
+}
+
+func rationalEntry(tag uint16, vals ...[2]uint32) tiffEntry {
+	var data []byte
+	for _, v := range vals {
+		data = binary.LittleEndian.AppendUint32(data, v[0])
+		data = binary.LittleEndian.AppendUint32(data, v[1])
+	}
+	return tiffEntry{tag, 5, uint32(len(vals)), data}
+}
+
+// buildTIFF 生成小端序 TIFF，exif 与 gps 为空时不写对应的子 IFD
+func buildTIFF(ifd0, exifIFD, gpsIFD []tiffEntry) []byte {
+	ifd0 = append([]tiffEntry{}, ifd0...)
+	ifds := [][]tiffEntry{nil}
+	pointers := map[int]int{} // 子 IFD 在 ifds 中的下标 -> ifd0 中指针项的下标
+	for _, sub := range []struct {
+		tag     uint16
+		entries []tiffEntry
+	}{{0x8769, exifIFD}, {0x8825, gpsIFD}} {
+		if len(sub.entries) > 0 {
+			pointers[len(ifds)] = len(ifd0)
+			ifd0 = append(ifd0, tiffEntry{sub.tag, 4, 1, make([]byte, 4)})
+			ifds = append(ifds, sub.entries)
+		}
+	}
+	ifds[0] = ifd0
+
+	offsets := make([]uint32, len(ifds))
+	off := uint32(8)
+	for i, entries := range ifds {
+		offsets[i] = off
+		off += uint32(2 + 12*len(entries) + 4)
+	}
+	for i, entry := range pointers {
+		binary.LittleEndian.PutUint32(ifd0[entry].data, offsets[i])
+	}
+
+	out := []byte{'I', 'I', 42, 0, 8, 0, 0, 0}
+	var values []byte
+	for _, entries := range ifds {
+		entries = append([]tiffEntry{}, entries...)
+		sort.Slice(entries, func(i, j int) bool { return entries[i].tag < entries[j].tag })
+		out = binary.LittleEndian.AppendUint16(out, uint16(len(entries)))
+		for _, e := range entries {
+			out = binary.LittleEndian.AppendUint16(out, e.tag)
+			out = binary.LittleEndian.AppendUint16(out, e.typ)
+			out = binary.LittleEndian.AppendUint32(out, e.count)
+			if len(e.data) <= 4 {
+				out = append(out, e.data...)
+				out = append(out, make([]byte, 4-len(e.data))...)
+			} else {
+				out = binary.LittleEndian.AppendUint32(out, off+uint32(len(values)))
+				values = append(values, e.data...)
+				if len(values)%2 != 0 {
+					values = append(values, 0)
+				}
+			}
+		}
+		out = binary.LittleEndian.AppendUint32(out, 0)
+	}
+	return append(out, values...)
+}
+
+// jpegWith 生成只含元数据段的 JPEG
+func jpegWith(segments ...[]byte) []byte {
+	out := []byte{0xFF, 0xD8}
+	for _, s := range segments {
+		out = append(out, s...)
+	}
+	return append(out, 0xFF, 0xD9)
+}
+
+func segment(marker byte, payload []byte) []byte {
+	out := []byte{0xFF, marker}
+	out = binary.BigEndian.AppendUint16(out, uint16(len(payload)+2))
+	return append(out, payload...)
+}
+
+func exifSegment(tiffData []byte) []byte {
+	return segment(0xE1, append([]byte(exifHeader), tiffData...))
+}
+
+func xmpSegment(xmp string) []byte {
+	return segment(0xE1, append([]byte("http://ns.adobe.com/xap/1.0/\x00"), xmp...))
+}
+
+// iptcSegment 生成 APP13，datasets 为记录 2 的 (数据集, 值)
+func iptcSegment(datasets ...struct {
+	id  byte
+	val string
+}) []byte {
+	var iptc []byte
+	for _, d := range datasets {
+		iptc = append(iptc, 0x1C, 2, d.id)
+		iptc = binary.BigEndian.AppendUint16(iptc, uint16(len(d.val)))
+		iptc = append(iptc, d.val...)
+	}
+	res := []byte("8BIM\x04\x04\x00\x00")
+	res = binary.BigEndian.AppendUint32(res, uint32(len(iptc)))
+	res = append(res, iptc...)
+	if len(iptc)%2 != 0 {
+		res = append(res, 0)
+	}
+	return segment(0xED, append([]byte("Photoshop 3.0\x00"), res...))
+}
+
+func dataset(id byte, val string) struct {
+	id  byte
+	val string
+} {
+	return struct {
+		id  byte
+		val string
+	}{id, val}
+}
+
+func TestExtractMetadata(t *testing.T) {
+	camera := []tiffEntry{
+		asciiEntry(0x010F, "Canon"),
+		asciiEntry(0x0110, "EOS R5"),
+		asciiEntry(0x010E, "Harbour at dusk"),
+	}
+	shot := []tiffEntry{
+		asciiEntry(0x9003, "2023:07:14 10:20:30"),
+		shortEntry(0x8827, 400),
+		rationalEntry(0x829D, [2]uint32{28, 10}),
+		rationalEntry(0x829A, [2]uint32{1, 250}),
+	}
+	gps := func(lat, lng [3][2]uint32, latRef, lngRef string) []tiffEntry {
+		return []tiffEntry{
+			asciiEntry(0x0001, latRef),
+			rationalEntry(0x0002, lat[0], lat[1], lat[2]),
+			asciiEntry(0x0003, lngRef),
+			rationalEntry(0x0004, lng[0], lng[1], lng[2]),
+		}
+	}
+	sydney := gps([3][2]uint32{{33, 1}, {51, 1}, {36, 1}}, [3][2]uint32{{151, 1}, {12, 1}, {36, 1}}, "S", "E")
+	nullIsland := gps([3][2]uint32{{0, 1}, {0, 1}, {0, 1}}, [3][2]uint32{{0, 1}, {0, 1}, {0, 1}}, "N", "E")
+	taken := time.Date(2023, 7, 14, 10, 20, 30, 0, time.Local)
+
+	xmp := `<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+<rdf:Description rdf:about="" xmlns:xmp="http://ns.adobe.com/xap/1.0/" xmlns:dc="http://purl.org/dc/elements/1.1/" xmp:Rating="4">
+<dc:description><rdf:Alt><rdf:li xml:lang="x-default">XMP description</rdf:li></rdf:Alt></dc:description>
+<dc:title><rdf:Alt><rdf:li xml:lang="x-default">XMP title</rdf:li></rdf:Alt></dc:title>
+<dc:subject><rdf:Bag><rdf:li>Beach</rdf:li><rdf:li>sunset</rdf:li></rdf:Bag></dc:subject>
+</rdf:Description></rdf:RDF></x:xmpmeta>`
+	iptc := iptcSegment(
+		dataset(5, "IPTC title"),
+		dataset(90, "Sydney"),
+		dataset(25, "beach"),
+		dataset(25, "family"),
+	)
+
+	cases := []struct {
+		name       string
+		data       []byte
+		wantTaken  *time.Time
+		wantLatLng *[2]float64
+		wantFields map[string]string // 只检查列出的字段，值为空表示不应存在
+	}{
+		{
+			name:       "exif with gps",
+			data:       jpegWith(exifSegment(buildTIFF(camera, shot, sydney))),
+			wantTaken:  &taken,
+			wantLatLng: &[2]float64{-(33 + 51.0/60 + 36.0/3600), 151 + 12.0/60 + 36.0/3600},
+			wantFields: map[string]string{
+				"make": "Canon", "model": "EOS R5", "iso": "400", "fNumber": "2.8",
+				"exposureTime": "0.004", "shutterSpeed": "1/250",
+			},
+		},
+		{
+			name:       "exif without gps",
+			data:       jpegWith(exifSegment(buildTIFF(camera, shot, nil))),
+			wantTaken:  &taken,
+			wantFields: map[string]string{"model": "EOS R5", "latitude": ""},
+		},
+		{
+			name:       "gps without time",
+			data:       jpegWith(exifSegment(buildTIFF(camera, nil, sydney))),
+			wantLatLng: &[2]float64{-(33 + 51.0/60 + 36.0/3600), 151 + 12.0/60 + 36.0/3600},
+			wantFields: map[string]string{"dateTime": ""},
+		},
+		{
+			name:       "zero gps ignored",
+			data:       jpegWith(exifSegment(buildTIFF(camera, shot, nullIsland))),
+			wantTaken:  &taken,
+			wantFields: map[string]string{"latitude": "", "longitude": ""},
+		},
+		{
+			name: "xmp and iptc without exif",
+			data: jpegWith(xmpSegment(xmp), iptc),
+			wantFields: map[string]string{
+				"rating": "4", "title": "XMP title", "description": "XMP description",
+				"city": "Sydney", "keywords": "Beach, sunset, family",
+			},
+		},
+		{
+			name:       "exif wins over xmp",
+			data:       jpegWith(exifSegment(buildTIFF(camera, nil, nil)), xmpSegment(xmp)),
+			wantFields: map[string]string{"description": "Harbour at dusk", "title": "XMP title"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			meta := ExtractMetadata(c.data, c.data)
+
+			switch {
+			case c.wantTaken == nil && meta.TakenAt != nil:
+				t.Errorf("TakenAt = %v, want nil", *meta.TakenAt)
+			case c.wantTaken != nil && (meta.TakenAt == nil || !meta.TakenAt.Equal(*c.wantTaken)):
+				t.Errorf("TakenAt = %v, want %v", meta.TakenAt, *c.wantTaken)
+			}
+
+			if c.wantLatLng == nil {
+				if meta.HasLocation() {
+					t.Errorf("location = (%f, %f), want none", *meta.Latitude, *meta.Longitude)
+				}
+			} else if !meta.HasLocation() ||
+				math.Abs(*meta.Latitude-c.wantLatLng[0]) > 1e-9 || math.Abs(*meta.Longitude-c.wantLatLng[1]) > 1e-9 {
+				t.Errorf("location = (%v, %v), want %v", meta.Latitude, meta.Longitude, *c.wantLatLng)
+			}
+
+			for key, want := range c.wantFields {
+				got, ok := meta.Fields[key]
+				if want == "" && ok {
+					t.Errorf("field %s = %q, want absent", key, got)
+				} else if want != "" && got != want {
+					t.Errorf("field %s = %q, want %q", key, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestJoinKeywords(t *testing.T) {
+	cases := []struct {
+		existing string
+		keywords []string
+		want     string
+	}{
+		{"", []string{"a", "b"}, "a, b"},
+		{"a, b", []string{"B", "c"}, "a, b, c"},
+		{"a", []string{" ", ""}, "a"},
+		{"", nil, ""},
+	}
+	for _, c := range cases {
+		if got := joinKeywords(c.existing, c.keywords); got != c.want {
+			t.Errorf("joinKeywords(%q, %q) = %q, want %q", c.existing, c.keywords, got, c.want)
+		}
+	}
+}
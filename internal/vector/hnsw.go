@@ -0,0 +1,145 @@
+package vector
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// IndexParams HNSW 建索引参数
+type IndexParams struct {
+	M              int
+	EfConstruction int
+}
+
+// SearchParams 单次 HNSW 查询参数，需在事务内通过 SET LOCAL 生效
+type SearchParams struct {
+	EfSearch      int    `json:"efSearch"`      // hnsw.ef_search，0 表示使用数据库默认值
+	IterativeScan string `json:"iterativeScan"` // hnsw.iterative_scan：off / strict_order / relaxed_order，空为自动
+	MaxScanTuples int    `json:"maxScanTuples"` // hnsw.max_scan_tuples，0 表示使用数据库默认值
+	Rerank        int    `json:"rerank"`        // 从索引取出的候选数量，不超过 MaxEfSearch，0 表示不额外取候选
+}
+
+const (
+	IterativeScanOff     = "off"
+	IterativeScanStrict  = "strict_order"
+	IterativeScanRelaxed = "relaxed_order"
+)
+
+// MaxEfSearch hnsw.ef_search 的上限，关闭迭代扫描时 HNSW 单次查询最多返回这么多条结果
+const MaxEfSearch = 1000
+
+const (
+	defaultM         = 16
+	defaultEfConst   = 200
+	defaultEfSearch  = 40
+	maxMaxScanTuples = 1000000
+)
+
+var (
+	indexParams   = IndexParams{M: defaultM, EfConstruction: defaultEfConst}
+	defaultSearch = SearchParams{EfSearch: defaultEfSearch}
+)
+
+// ConfigureHNSW 从配置字符串设置建索引参数与默认 ef_search，空字符串使用默认值
+func ConfigureHNSW(m, efConstruction, efSearch string) {
+	indexParams = IndexParams{
+		M:              parseIntOrDefault("HNSW_M", m, defaultM),
+		EfConstruction: parseIntOrDefault("HNSW_EF_CONSTRUCTION", efConstruction, defaultEfConst),
+	}
+	defaultSearch = SearchParams{EfSearch: parseIntOrDefault("HNSW_EF_SEARCH", efSearch, defaultEfSearch)}
+	if err := defaultSearch.Validate(); err != nil {
+		log.Fatal("HNSW configuration failed:", err)
+	}
+	log.Printf("HNSW m: %d, ef_construction: %d, ef_search: %d",
+		indexParams.M, indexParams.EfConstruction, defaultSearch.EfSearch)
+}
+
+func parseIntOrDefault(name, val string, def int) int {
+	if val == "" {
+		return def
+	}
+	n, err := strconv.Atoi(val)
+	if err != nil || n <= 0 {
+		log.Fatalf("Invalid %s: %s", name, val)
+	}
+	return n
+}
+
+// ActiveIndexParams 当前建索引参数
+func ActiveIndexParams() IndexParams {
+	return indexParams
+}
+
+// DefaultSearchParams 默认查询参数
+func DefaultSearchParams() SearchParams {
+	return defaultSearch
+}
+
+// Validate 校验查询参数，防止拼接进 SET LOCAL 的值越界
+func (p SearchParams) Validate() error {
+	if p.EfSearch < 0 || p.EfSearch > MaxEfSearch {
+		return fmt.Errorf("efSearch must be within [0, %d]", MaxEfSearch)
+	}
+	switch p.IterativeScan {
+	case "", IterativeScanOff, IterativeScanStrict, IterativeScanRelaxed:
+	default:
+		return fmt.Errorf("unknown iterativeScan: %s", p.IterativeScan)
+	}
+	if p.MaxScanTuples < 0 || p.MaxScanTuples > maxMaxScanTuples {
+		return fmt.Errorf("maxScanTuples must be within [0, %d]", maxMaxScanTuples)
+	}
+	// 候选数受 ef_search 限制，超过上限的部分取不到
+	if p.Rerank < 0 || p.Rerank > MaxEfSearch {
+		return fmt.Errorf("rerank must be within [0, %d]", MaxEfSearch)
+	}
+	return nil
+}
+
+// ForFilter 带过滤条件时 HNSW 可能在过滤后返回不足 k 条，未显式指定时开启迭代扫描
+func (p SearchParams) ForFilter(filtered bool) SearchParams {
+	if p.IterativeScan == "" {
+		if filtered {
+			p.IterativeScan = IterativeScanRelaxed
+		} else {
+			p.IterativeScan = IterativeScanOff
+		}
+	}
+	return p
+}
+
+// Candidates 从索引取出的候选数量，至少为 window
+func (p SearchParams) Candidates(window int) int {
+	return max(window, p.Rerank)
+}
+
+// ForCandidates ef_search 小于候选数时 HNSW 返回的结果会不足，按候选数调高。
+// 未指定 ef_search 时数据库默认值（40）同样可能不足，一并设置
+func (p SearchParams) ForCandidates(n int) SearchParams {
+	if p.EfSearch < n {
+		p.EfSearch = min(n, MaxEfSearch)
+	}
+	return p
+}
+
+// Apply 在事务内设置本次查询的 HNSW 参数
+func (p SearchParams) Apply(tx *gorm.DB) error {
+	if p.EfSearch > 0 {
+		if err := tx.Exec(fmt.Sprintf("SET LOCAL hnsw.ef_search = %d", p.EfSearch)).Error; err != nil {
+			return err
+		}
+	}
+	if p.IterativeScan != "" {
+		if err := tx.Exec(fmt.Sprintf("SET LOCAL hnsw.iterative_scan = %s", p.IterativeScan)).Error; err != nil {
+			return err
+		}
+	}
+	if p.MaxScanTuples > 0 {
+		if err := tx.Exec(fmt.Sprintf("SET LOCAL hnsw.max_scan_tuples = %d", p.MaxScanTuples)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package vector
+
+import "testing"
+
+func TestSearchParamsValidate(t *testing.T) {
+	cases := []struct {
+		name    string
+		params  SearchParams
+		wantErr bool
+	}{
+		{"defaults", SearchParams{}, false},
+		{"max values", SearchParams{EfSearch: MaxEfSearch, MaxScanTuples: maxMaxScanTuples, Rerank: MaxEfSearch}, false},
+		{"negative efSearch", SearchParams{EfSearch: -1}, true},
+		{"efSearch too large", SearchParams{EfSearch: MaxEfSearch + 1}, true},
+		{"unknown iterativeScan", SearchParams{IterativeScan: "loose"}, true},
+		{"maxScanTuples too large", SearchParams{MaxScanTuples: maxMaxScanTuples + 1}, true},
+		{"rerank beyond efSearch limit", SearchParams{Rerank: MaxEfSearch + 1}, true},
+	}
+	for _, c := range cases {
+		if err := c.params.Validate(); (err != nil) != c.wantErr {
+			t.Errorf("%s: Validate() = %v, wantErr %v", c.name, err, c.wantErr)
+		}
+	}
+}
+
+func TestForCandidates(t *testing.T) {
+	cases := []struct {
+		efSearch   int
+		candidates int
+		want       int
+	}{
+		{40, 10, 40},
+		{40, 200, 200},
+		{0, 200, 200},
+		{0, 0, 0},
+		{100, MaxEfSearch + 1, MaxEfSearch},
+	}
+	for _, c := range cases {
+		got := SearchParams{EfSearch: c.efSearch}.ForCandidates(c.candidates).EfSearch
+		if got != c.want {
+			t.Errorf("ForCandidates(%d) with efSearch %d = %d, want %d", c.candidates, c.efSearch, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package vector
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strings"
+)
+
+// Metric 向量距离度量
+type Metric string
+
+const (
+	L2           Metric = "l2"
+	Cosine       Metric = "cosine"
+	InnerProduct Metric = "ip"
+)
+
+// ParseMetric 解析配置中的度量名称，空字符串为 L2
+func ParseMetric(name string) (Metric, error) {
+	switch Metric(strings.ToLower(strings.TrimSpace(name))) {
+	case "", L2:
+		return L2, nil
+	case Cosine:
+		return Cosine, nil
+	case InnerProduct, "inner_product":
+		return InnerProduct, nil
+	default:
+		return "", fmt.Errorf("unknown vector metric: %s", name)
+	}
+}
+
+// Operator pgvector 距离运算符
+func (m Metric) Operator() string {
+	switch m {
+	case Cosine:
+		return "<=>"
+	case InnerProduct:
+		return "<#>"
+	default:
+		return "<->"
+	}
+}
+
+// OpClass HNSW 索引对应的运算符类
+func (m Metric) OpClass() string {
+	switch m {
+	case Cosine:
+		return "vector_cosine_ops"
+	case InnerProduct:
+		return "vector_ip_ops"
+	default:
+		return "vector_l2_ops"
+	}
+}
+
+// Similarity 距离转相似度，越大越相似
+func (m Metric) Similarity(distance float64) float64 {
+	switch m {
+	case Cosine:
+		// <=> 返回 1 - cos
+		return 1 - distance
+	case InnerProduct:
+		// <#> 返回负内积
+		return -distance
+	default:
+		return 1 / (1 + distance)
+	}
+}
+
+// Distance Similarity 的反函数，用于把相似度阈值下推到 SQL
+func (m Metric) Distance(similarity float64) float64 {
+	switch m {
+	case Cosine:
+		return 1 - similarity
+	case InnerProduct:
+		return -similarity
+	default:
+		return 1/similarity - 1
+	}
+}
+
+type config struct {
+	metric    Metric
+	normalize bool
+}
+
+var active = config{metric: L2}
+
+// Configure 设置全局度量以及写入前是否归一化
+func Configure(metricName string, normalize bool) {
+	metric, err := ParseMetric(metricName)
+	if err != nil {
+		log.Fatal("Vector metric configuration failed:", err)
+	}
+	active = config{metric: metric, normalize: normalize}
+	log.Printf("Vector metric: %s, normalize: %v", metric, normalize)
+}
+
+// ActiveMetric 当前使用的度量
+func ActiveMetric() Metric {
+	return active.metric
+}
+
+// NormalizeEnabled 写入前是否归一化
+func NormalizeEnabled() bool {
+	return active.normalize
+}
+
+// Prepare 按配置处理写入或查询用的 embedding
+func Prepare(embedding []float32) []float32 {
+	if !active.normalize {
+		return embedding
+	}
+	return Normalize(embedding)
+}
+
+// Normalize 返回 L2 归一化后的副本，零向量原样返回
+func Normalize(embedding []float32) []float32 {
+	var sum float64
+	for _, v := range embedding {
+		sum += float64(v) * float64(v)
+	}
+	if sum == 0 {
+		return embedding
+	}
+	norm := math.Sqrt(sum)
+	out := make([]float32, len(embedding))
+	for i, v := range embedding {
+		out[i] = float32(float64(v) / norm)
+	}
+	return out
+}
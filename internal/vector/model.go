@@ -0,0 +1,121 @@
+package vector
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Model 向量模型，名称 + 版本唯一确定一组可互相比较的 embedding
+type Model struct {
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	Dimension int    `json:"dimension"`
+}
+
+// legacyModel 旧版 files.vector 固定为 512 维
+var legacyModel = Model{Name: "default", Version: "1", Dimension: 512}
+
+var (
+	activeModel = legacyModel
+	targetModel *Model
+)
+
+var identRe = regexp.MustCompile(`[^a-z0-9]+`)
+
+// ParseModel 解析 name:version:dimension 形式的模型配置
+func ParseModel(spec string) (Model, error) {
+	parts := strings.Split(strings.TrimSpace(spec), ":")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" {
+		return Model{}, fmt.Errorf("model spec must be name:version:dimension, got %q", spec)
+	}
+	dim, err := strconv.Atoi(parts[2])
+	if err != nil || dim <= 0 || dim > 16000 {
+		return Model{}, fmt.Errorf("invalid model dimension: %s", parts[2])
+	}
+	return Model{Name: parts[0], Version: parts[1], Dimension: dim}, nil
+}
+
+// ConfigureModels 设置搜索使用的模型以及可选的重新生成目标模型，active 为空时沿用旧版模型
+func ConfigureModels(active, target string) {
+	if active != "" {
+		m, err := ParseModel(active)
+		if err != nil {
+			log.Fatal("Active embedding model configuration failed:", err)
+		}
+		activeModel = m
+	}
+	targetModel = nil
+	if target != "" {
+		m, err := ParseModel(target)
+		if err != nil {
+			log.Fatal("Re-embedding model configuration failed:", err)
+		}
+		if m.Key() != activeModel.Key() {
+			targetModel = &m
+		}
+	}
+	log.Printf("Active embedding model: %s", activeModel.Key())
+	if targetModel != nil {
+		log.Printf("Re-embedding target model: %s", targetModel.Key())
+	}
+}
+
+// ActiveModel 搜索使用的模型
+func ActiveModel() Model {
+	return activeModel
+}
+
+// LegacyModel 旧版 files.vector 对应的模型
+func LegacyModel() Model {
+	return legacyModel
+}
+
+// TargetModel 正在迁移到的模型
+func TargetModel() (Model, bool) {
+	if targetModel == nil {
+		return Model{}, false
+	}
+	return *targetModel, true
+}
+
+// Key name@version
+func (m Model) Key() string {
+	return m.Name + "@" + m.Version
+}
+
+// Column 带维度的向量表达式，查询与表达式索引必须一致才能走 HNSW
+func (m Model) Column() string {
+	return fmt.Sprintf("(vector::vector(%d))", m.Dimension)
+}
+
+// Predicate 部分索引的谓词。以字面量内联到查询中，
+// 使用绑定参数时通用执行计划无法匹配部分索引
+func (m Model) Predicate() string {
+	return fmt.Sprintf("model_name = %s AND model_version = %s", quoteLiteral(m.Name), quoteLiteral(m.Version))
+}
+
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// IndexName 该模型的 HNSW 索引名
+func (m Model) IndexName() string {
+	ident := identRe.ReplaceAllString(strings.ToLower(m.Name+"_"+m.Version), "_")
+	name := "idx_embeddings_hnsw_" + strings.Trim(ident, "_")
+	// PostgreSQL 标识符最长 63 字节
+	if len(name) > 63 {
+		name = name[:63]
+	}
+	return name
+}
+
+// Check 校验 embedding 维度
+func (m Model) Check(embedding []float32) error {
+	if len(embedding) != m.Dimension {
+		return fmt.Errorf("model %s expects %d dimensions, got %d", m.Key(), m.Dimension, len(embedding))
+	}
+	return nil
+}